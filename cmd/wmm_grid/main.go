@@ -0,0 +1,337 @@
+// wmm_grid estimates the strength and direction of Earth's main Magnetic field
+// over a grid of locations and times.
+//
+// Usage is
+//  wmm_grid --cof_file=WMM2020.COF --component=D --output=grid.txt
+//    [min lat] [max lat] [lat step] [min lng] [max lng] [lng step]
+//    [min alt] [max alt] [alt step] [start date] [end date] [date step]
+//
+// If no locations and dates are given, they are requested interactively.
+// If the minimum latitude is negative, precede it with -- so that it is
+// not taken for a flag.
+//
+// The World Magnetic Model (WMM) for 2020
+// is a model of Earth's main Magnetic field.  The WMM
+// is recomputed every five (5) years, in years divisible by
+// five (i.e. 2010, 2015, 2020).
+//
+// Information on the model is available at https://www.ngdc.noaa.gov/geomag/WMM/DoDWMM.shtml
+//
+// Input required is the range of locations in geodetic latitude and
+// longitude (positive for northern latitudes and eastern
+// longitudes) and altitude in kilometers, each with a step size,
+// and the range of dates of interest in decimal years with a step size.
+// Altitudes are above mean sea level unless prefixed by E, in which case
+// they are above the WGS-84 ellipsoid.
+//
+// The program computes a single magnetic element at every grid point.
+// The element is chosen with the --component flag and may be one of
+// X, Y, Z, H, F, D (Declination), I (Inclination), GV (Grid Variation),
+// or the rate of change of any of these, prefixed with D,
+// e.g. DX or DGV.  Field strengths are in nT and angles in degrees.
+//
+// Output is one line per grid point, with whitespace-separated columns for
+// the latitude, longitude, altitude, decimal year and the element value,
+// preceded by a header line starting with #.
+//
+// Sample output of
+//  wmm_grid -e D 30 31 1 -90 -89 1 E0 E0 0 2020 2021 1
+//
+//  # Latitude Longitude Altitude(km) Date D(deg)
+//    30.0000  -90.0000   0.0000 2020.0000     -1.0594
+//    30.0000  -90.0000   0.0000 2021.0000     -1.1585
+//    30.0000  -89.0000   0.0000 2020.0000     -1.7122
+//    30.0000  -89.0000   0.0000 2021.0000     -1.8091
+//    31.0000  -90.0000   0.0000 2020.0000     -1.1396
+//    31.0000  -90.0000   0.0000 2021.0000     -1.2362
+//    31.0000  -89.0000   0.0000 2020.0000     -1.8016
+//    31.0000  -89.0000   0.0000 2021.0000     -1.8959
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/westphae/geomag/internal/util"
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+const (
+	usage = "wmm_grid --cof_file=WMM2020.COF --component=D --output=grid.txt " +
+		"[min lat] [max lat] [lat step] [min lng] [max lng] [lng step] " +
+		"[min alt] [max alt] [alt step] [start date] [end date] [date step]"
+	cofUsage       = "COF coefficients file to use, empty for the built-in one"
+	componentUsage = "Magnetic element to output: X, Y, Z, H, F, D, I, GV or their rates DX, DY, DZ, DH, DF, DD, DI, DGV"
+	outputUsage    = "File to write the grid to, empty for standard output"
+)
+
+var prompts = []string{
+	"Please enter minimum latitude, North latitude positive (in decimal degrees or D,M,S). ",
+	"Please enter maximum latitude, North latitude positive (in decimal degrees or D,M,S). ",
+	"Please enter latitude step size (in decimal degrees). ",
+	"Please enter minimum longitude, East longitude positive, West negative (in decimal degrees or D,M,S). ",
+	"Please enter maximum longitude, East longitude positive, West negative (in decimal degrees or D,M,S). ",
+	"Please enter longitude step size (in decimal degrees). ",
+	"Please enter minimum height above mean sea level (in kilometers). " +
+		"[For height above WGS-84 Ellipsoid prefix E, for example (E20.1)]. ",
+	"Please enter maximum height (in kilometers). ",
+	"Please enter height step size (in kilometers). ",
+	"Please enter the start date as decimal year or calendar date (YYYY.yyy, MM DD YYYY or MM/DD/YYYY) ",
+	"Please enter the end date as decimal year or calendar date (YYYY.yyy, MM DD YYYY or MM/DD/YYYY) ",
+	"Please enter the date step size (in decimal years). ",
+}
+
+type component struct {
+	unit  string
+	value func(mf wmm.MagneticField, loc egm96.Location) float64
+}
+
+var components = map[string]component{
+	"X": {"nT", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		x, _, _, _, _, _ := mf.Ellipsoidal()
+		return x
+	}},
+	"Y": {"nT", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		_, y, _, _, _, _ := mf.Ellipsoidal()
+		return y
+	}},
+	"Z": {"nT", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		_, _, z, _, _, _ := mf.Ellipsoidal()
+		return z
+	}},
+	"H":  {"nT", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.H() }},
+	"F":  {"nT", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.F() }},
+	"D":  {"deg", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.D() }},
+	"I":  {"deg", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.I() }},
+	"GV": {"deg", func(mf wmm.MagneticField, loc egm96.Location) float64 { return mf.GV(loc) }},
+	"DX": {"nT/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		_, _, _, dx, _, _ := mf.Ellipsoidal()
+		return dx
+	}},
+	"DY": {"nT/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		_, _, _, _, dy, _ := mf.Ellipsoidal()
+		return dy
+	}},
+	"DZ": {"nT/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 {
+		_, _, _, _, _, dz := mf.Ellipsoidal()
+		return dz
+	}},
+	"DH":  {"nT/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.DH() }},
+	"DF":  {"nT/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.DF() }},
+	"DD":  {"deg/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.DD() }},
+	"DI":  {"deg/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.DI() }},
+	"DGV": {"deg/yr", func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.DGV() }},
+}
+
+var (
+	cofFile       string
+	componentName string
+	outputFile    string
+	lats, lngs    []float64
+	alts          []float64
+	hae           bool
+	dYears        []float64
+	ErrHelp       error
+)
+
+func init() {
+	flag.StringVar(&cofFile, "cof_file", "", cofUsage)
+	flag.StringVar(&cofFile, "c", "", cofUsage)
+
+	flag.StringVar(&componentName, "component", "D", componentUsage)
+	flag.StringVar(&componentName, "e", "D", componentUsage)
+
+	flag.StringVar(&outputFile, "output", "", outputUsage)
+	flag.StringVar(&outputFile, "o", "", outputUsage)
+
+	ErrHelp = errors.New(usage)
+}
+
+func main() {
+	var err error
+
+	flag.Parse()
+
+	comp, ok := components[strings.ToUpper(componentName)]
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "Unknown component %s\n%s\n", componentName, componentUsage)
+		os.Exit(1)
+	}
+	componentName = strings.ToUpper(componentName)
+
+	model := wmm.DefaultModel()
+	if cofFile!="" {
+		if model, err = wmm.LoadModel(cofFile); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	inputs := make([]string, len(prompts))
+	if flag.NArg() == 0 {
+		for i := range inputs {
+			inputs[i] = readUserInput(prompts[i])
+			if inputs[i] == "q" {
+				fmt.Println("Goodbye")
+				os.Exit(0)
+			}
+		}
+	} else if flag.NArg() == len(prompts) {
+		for i := range inputs {
+			inputs[i] = flag.Arg(i)
+		}
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, "You must specify a latitude, longitude, altitude and date range in that order")
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	if err = parseInputs(inputs); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	out := io.Writer(os.Stdout)
+	if outputFile!="" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	times := make([]time.Time, len(dYears))
+	for i, dYear := range dYears {
+		times[i] = wmm.DecimalYear(dYear).ToTime()
+	}
+
+	_, _ = fmt.Fprintf(w, "# Latitude Longitude Altitude(km) Date %s(%s)\n", componentName, comp.unit)
+	printLine := func(lat, lng, alt, dYear float64, mf wmm.MagneticField, loc egm96.Location) {
+		_, _ = fmt.Fprintf(w, "%9.4f %9.4f %8.4f %9.4f %11.4f\n", lat, lng, alt, dYear, comp.value(mf, loc))
+	}
+
+	if hae {
+		// Heights above the ellipsoid form a regular grid that can be calculated at once.
+		heights := make([]float64, len(alts))
+		for i, alt := range alts {
+			heights[i] = alt*1000
+		}
+		grid, err := model.MagneticFieldGrid(lats, lngs, heights, times)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		for i, lat := range lats {
+			for j, lng := range lngs {
+				for k, alt := range alts {
+					loc := egm96.NewLocationGeodetic(lat, lng, heights[k])
+					for l, dYear := range dYears {
+						printLine(lat, lng, alt, dYear, grid.At(i, j, k, l), loc)
+					}
+				}
+			}
+		}
+		return
+	}
+
+	// Heights above MSL depend on the geoid height at each location.
+	warned := false
+	for _, lat := range lats {
+		for _, lng := range lngs {
+			for _, alt := range alts {
+				loc, err := egm96.NewLocationMSL(lat, lng, alt*1000)
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Error making location: %s\n", err)
+					_ = w.Flush()
+					os.Exit(1)
+				}
+				for l, dYear := range dYears {
+					mf, err := model.MagneticField(loc, times[l])
+					if err != nil && !warned {
+						_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+						warned = true
+					}
+					printLine(lat, lng, alt, dYear, mf, loc)
+				}
+			}
+		}
+	}
+}
+
+// parseInputs parses the minimum, maximum and step of latitude, longitude,
+// altitude and date, in that order, into the grid axes.
+func parseInputs(inputs []string) (err error) {
+	v := make([]float64, len(inputs))
+
+	for i:=0; i<6; i++ {
+		if v[i], err = parsing.ParseLatLng(inputs[i]); err != nil {
+			return err
+		}
+	}
+	if v[6], hae, err = parsing.ParseAltitude(inputs[6]); err != nil {
+		return err
+	}
+	for i:=7; i<9; i++ {
+		if v[i], _, err = parsing.ParseAltitude(strings.TrimPrefix(inputs[i], "E")); err != nil {
+			return err
+		}
+	}
+	for i:=9; i<12; i++ {
+		if v[i], err = parsing.ParseTime(inputs[i]); err != nil {
+			return err
+		}
+	}
+
+	if v[0] < -90 || v[1] > 90 {
+		return fmt.Errorf("latitudes must be in the range -90 to 90")
+	}
+	if lats, err = axis("latitude", v[0], v[1], v[2]); err != nil {
+		return err
+	}
+	if lngs, err = axis("longitude", v[3], v[4], v[5]); err != nil {
+		return err
+	}
+	if alts, err = axis("altitude", v[6], v[7], v[8]); err != nil {
+		return err
+	}
+	if dYears, err = axis("date", v[9], v[10], v[11]); err != nil {
+		return err
+	}
+	return nil
+}
+
+// axis returns the values from min to max inclusive in increments of step.
+func axis(name string, min, max, step float64) (vs []float64, err error) {
+	if max<min {
+		return nil, fmt.Errorf("maximum %s %v is less than minimum %v", name, max, min)
+	}
+	if max==min {
+		return []float64{min}, nil
+	}
+	if step<=0 {
+		return nil, fmt.Errorf("%s step size must be positive", name)
+	}
+	n := int(math.Floor((max-min)/step+1e-9))
+	for i:=0; i<=n; i++ {
+		vs = append(vs, min+float64(i)*step)
+	}
+	return vs, nil
+}
+
+func readUserInput(prompt string) (inp string) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print(prompt)
+	inp, _ = reader.ReadString('\n')
+	inp = strings.TrimSpace(inp)
+	return inp
+}
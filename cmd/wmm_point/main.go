@@ -88,8 +88,12 @@ const (
 	cofUsage = "COF coefficients file to use, empty for the built-in one"
 	sphericalUsage = "Output spherical values instead of ellipsoidal"
 	lngErr = "Error: Degree input is outside legal range. The legal range is from -180 to 360."
-	fieldWarn = "Warning: The Horizontal Field strength at this location is only 0.000000. " +
-		"Compass readings have VERY LARGE uncertainties in areas where where H is smaller than 1000 nT"
+	blackoutWarn = "Warning: The Horizontal Field strength at this location is only %f\n" +
+		"\tCompass readings have VERY LARGE uncertainties in areas where\n" +
+		"\twhere H is smaller than 2000 nT\n"
+	cautionWarn = "Warning: The Horizontal Field strength at this location is only %f\n" +
+		"\tCompass readings have large uncertainties in areas where H\n" +
+		"\tis smaller than 6000 nT\n"
 )
 
 var prompt = map[string]string{
@@ -250,6 +254,15 @@ func main() {
 		fmt.Println()
 		fmt.Printf("Grid Variation =  %2.0fº %2.0f'\n", gvD, gvM+gvS/60)
 	}
+
+	switch mf.Zone() {
+	case wmm.BlackoutZone:
+		fmt.Println()
+		fmt.Printf(blackoutWarn, mf.H())
+	case wmm.CautionZone:
+		fmt.Println()
+		fmt.Printf(cautionWarn, mf.H())
+	}
 }
 
 func userInput() {
@@ -0,0 +1,275 @@
+// Package contour draws lines of equal value of a component of the magnetic
+// field over a region, as printed on magnetic charts: isogonic lines of equal
+// declination, isoclinic lines of equal inclination, isodynamic lines of equal
+// field strength, and the agonic line of zero declination.
+//
+// The component is evaluated on a regular latitude and longitude grid with
+// the grid evaluator of package wmm, and the lines are found by marching
+// squares with linear interpolation along the grid cell edges.
+// They can be written as GeoJSON or KML for use with mapping software.
+package contour
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+// Component is a component of the magnetic field to be contoured.
+type Component struct {
+	Name  string  // The short name of the component, e.g. D
+	Title string  // The name of the lines of equal value, e.g. Isogonic
+	Units string  // The units of the component, e.g. deg
+	Wrap  float64 // The period of angular components, e.g. 360, or 0
+	Value func(mf wmm.MagneticField, loc egm96.Location) float64
+}
+
+// Components commonly drawn on magnetic charts.
+var (
+	Declination = Component{"D", "Isogonic", "deg", 360,
+		func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.D() }}
+	Inclination = Component{"I", "Isoclinic", "deg", 0,
+		func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.I() }}
+	TotalIntensity = Component{"F", "Isodynamic", "nT", 0,
+		func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.F() }}
+	HorizontalIntensity = Component{"H", "Isodynamic", "nT", 0,
+		func(mf wmm.MagneticField, _ egm96.Location) float64 { return mf.H() }}
+	VerticalIntensity = Component{"Z", "Isodynamic", "nT", 0,
+		func(mf wmm.MagneticField, _ egm96.Location) float64 {
+			_, _, z, _, _, _ := mf.Ellipsoidal()
+			return z
+		}}
+	GridVariation = Component{"GV", "Isogriv", "deg", 360,
+		func(mf wmm.MagneticField, loc egm96.Location) float64 { return mf.GV(loc) }}
+)
+
+// Region is the area over which to draw contours.
+type Region struct {
+	MinLat, MaxLat float64 // Range of geodetic latitudes, º
+	MinLng, MaxLng float64 // Range of longitudes, º
+	Resolution     float64 // Spacing of the grid on which the component is evaluated, º
+	Height         float64 // Height above the WGS84 ellipsoid, m
+}
+
+// World is the whole globe at one degree resolution on the ellipsoid.
+var World = Region{MinLat: -89, MaxLat: 89, MinLng: -180, MaxLng: 180, Resolution: 1}
+
+// Point is a point of a contour line.
+type Point struct {
+	Lat, Lng float64 // Geodetic latitude and longitude, º
+}
+
+// Line is a contour line of a single value of a component.
+// A closed line has the same first and last point.
+type Line struct {
+	Level  float64
+	Points []Point
+}
+
+// Levels returns the multiples of step from lo to hi, e.g. the levels
+// -20, -10, 0, 10, 20 for step 10 from -25 to 25.
+//
+// Each level is calculated from its multiple of step, so that rounding
+// errors do not accumulate over many levels.
+func Levels(step, lo, hi float64) (levels []float64) {
+	k0 := math.Ceil(lo/step)
+	for i:=0.0; (k0+i)*step<=hi; i++ {
+		levels = append(levels, (k0+i)*step)
+	}
+	return levels
+}
+
+// Contours calculates the contour lines of the component at the levels over
+// the region at the input time, using the Model w, or the default Model if w is nil.
+//
+// For Declination the agonic line, level 0, is always included.
+// Angular components wrap at ±180º, and grid cells across the wrap, such as
+// around the magnetic poles, are not contoured.
+//
+// As for wmm.CalculateWMMMagneticFieldGrid, an informational error is
+// returned if the time or height is outside of the validity of the Model,
+// but the contours are still calculated.
+func Contours(w *wmm.Model, c Component, r Region, levels []float64, t time.Time) (lines []Line, err error) {
+	if w==nil {
+		w = wmm.DefaultModel()
+	}
+	if r.Resolution<=0 || r.MaxLat<=r.MinLat || r.MaxLng<=r.MinLng {
+		return nil, fmt.Errorf("bad contour region %+v", r)
+	}
+	if c.Name==Declination.Name && !hasLevel(levels, 0) {
+		levels = append([]float64{0}, levels...)
+	}
+
+	lats := axis(r.MinLat, r.MaxLat, r.Resolution)
+	lngs := axis(r.MinLng, r.MaxLng, r.Resolution)
+	grid, err := w.MagneticFieldGrid(lats, lngs, []float64{r.Height}, []time.Time{t})
+	values := make([][]float64, len(lats))
+	for i, lat := range lats {
+		values[i] = make([]float64, len(lngs))
+		for j, lng := range lngs {
+			values[i][j] = c.Value(grid.At(i, j, 0, 0), egm96.NewLocationGeodetic(lat, lng, r.Height))
+		}
+	}
+
+	for _, level := range levels {
+		for _, pts := range march(lats, lngs, values, level, c.Wrap) {
+			lines = append(lines, Line{Level: level, Points: pts})
+		}
+	}
+	return lines, err
+}
+
+func hasLevel(levels []float64, l float64) bool {
+	for _, v := range levels {
+		if v==l {
+			return true
+		}
+	}
+	return false
+}
+
+// axis returns evenly spaced values from lo to hi, inclusive, at about step apart.
+func axis(lo, hi, step float64) (v []float64) {
+	n := int(math.Round((hi-lo)/step))
+	if n<1 {
+		n = 1
+	}
+	for i:=0; i<=n; i++ {
+		v = append(v, lo+(hi-lo)*float64(i)/float64(n))
+	}
+	return v
+}
+
+// march returns the lines on which the values on the grid of latitudes and
+// longitudes equal the level, by marching squares.
+// Cells whose values span more than half of a non-zero wrap are skipped.
+func march(lats, lngs []float64, v [][]float64, level, wrap float64) (lines [][]Point) {
+	nLng := len(lngs)
+	// Each crossing lies on a cell edge, identified by its lower-left corner
+	// and whether it runs east (0) or north (1) from there.
+	hEdge := func(i, j int) int { return (i*nLng+j)*2 }
+	vEdge := func(i, j int) int { return (i*nLng+j)*2+1 }
+	points := make(map[int]Point)
+	crossing := func(e, i0, j0, i1, j1 int) {
+		if _, ok := points[e]; ok {
+			return
+		}
+		f := (level-v[i0][j0])/(v[i1][j1]-v[i0][j0])
+		points[e] = Point{
+			Lat: lats[i0] + f*(lats[i1]-lats[i0]),
+			Lng: lngs[j0] + f*(lngs[j1]-lngs[j0]),
+		}
+	}
+
+	var segments [][2]int
+	for i:=0; i<len(lats)-1; i++ {
+		for j:=0; j<nLng-1; j++ {
+			a, b, c, d := v[i][j], v[i][j+1], v[i+1][j+1], v[i+1][j]
+			if wrap>0 && math.Max(math.Max(a, b), math.Max(c, d))-math.Min(math.Min(a, b), math.Min(c, d))>wrap/2 {
+				continue
+			}
+			cell := 0
+			for k, x := range []float64{a, b, c, d} {
+				if x>=level {
+					cell |= 1<<uint(k)
+				}
+			}
+			if cell==0 || cell==15 {
+				continue
+			}
+
+			bottom, right, top, left := hEdge(i, j), vEdge(i, j+1), hEdge(i+1, j), vEdge(i, j)
+			if (a>=level)!=(b>=level) {
+				crossing(bottom, i, j, i, j+1)
+			}
+			if (b>=level)!=(c>=level) {
+				crossing(right, i, j+1, i+1, j+1)
+			}
+			if (d>=level)!=(c>=level) {
+				crossing(top, i+1, j, i+1, j+1)
+			}
+			if (a>=level)!=(d>=level) {
+				crossing(left, i, j, i+1, j)
+			}
+
+			// Saddles are resolved by the average value at the center of the cell
+			center := (a+b+c+d)/4>=level
+			switch cell {
+			case 1, 14:
+				segments = append(segments, [2]int{left, bottom})
+			case 2, 13:
+				segments = append(segments, [2]int{bottom, right})
+			case 3, 12:
+				segments = append(segments, [2]int{left, right})
+			case 4, 11:
+				segments = append(segments, [2]int{right, top})
+			case 6, 9:
+				segments = append(segments, [2]int{bottom, top})
+			case 7, 8:
+				segments = append(segments, [2]int{left, top})
+			case 5:
+				if center {
+					segments = append(segments, [2]int{bottom, right}, [2]int{top, left})
+				} else {
+					segments = append(segments, [2]int{left, bottom}, [2]int{right, top})
+				}
+			case 10:
+				if center {
+					segments = append(segments, [2]int{left, bottom}, [2]int{right, top})
+				} else {
+					segments = append(segments, [2]int{bottom, right}, [2]int{top, left})
+				}
+			}
+		}
+	}
+
+	// Join the segments sharing an edge into lines
+	atEdge := make(map[int][]int)
+	for k, s := range segments {
+		atEdge[s[0]] = append(atEdge[s[0]], k)
+		atEdge[s[1]] = append(atEdge[s[1]], k)
+	}
+	used := make([]bool, len(segments))
+	follow := func(e int) (edges []int) {
+		for {
+			next := -1
+			for _, k := range atEdge[e] {
+				if !used[k] {
+					next = k
+					break
+				}
+			}
+			if next<0 {
+				return edges
+			}
+			used[next] = true
+			if segments[next][0]==e {
+				e = segments[next][1]
+			} else {
+				e = segments[next][0]
+			}
+			edges = append(edges, e)
+		}
+	}
+	for k, s := range segments {
+		if used[k] {
+			continue
+		}
+		used[k] = true
+		fwd := follow(s[1])
+		back := follow(s[0])
+		var line []Point
+		for n:=len(back)-1; n>=0; n-- {
+			line = append(line, points[back[n]])
+		}
+		line = append(line, points[s[0]], points[s[1]])
+		for _, e := range fwd {
+			line = append(line, points[e])
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,160 @@
+package contour
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+func testDiff(name string, actual, expected float64, eps float64, t *testing.T) {
+	if actual - expected > -eps && actual - expected < eps {
+		t.Logf("%s correct: expected %8.4f, got %8.4f", name, expected, actual)
+		return
+	}
+	t.Errorf("%s incorrect: expected %8.4f, got %8.4f", name, expected, actual)
+}
+
+func TestLevels(t *testing.T) {
+	levels := Levels(10, -25, 25)
+	expected := []float64{-20, -10, 0, 10, 20}
+	if len(levels)!=len(expected) {
+		t.Fatalf("expected levels %v, got %v", expected, levels)
+	}
+	for i := range levels {
+		testDiff("level", levels[i], expected[i], 1e-9, t)
+	}
+
+	// Fractional steps reach the last level and are exact multiples of the step
+	levels = Levels(0.1, 0, 1)
+	if len(levels)!=11 {
+		t.Fatalf("expected 11 levels from 0 to 1, got %v", levels)
+	}
+	for i := range levels {
+		if levels[i]!=float64(i)*0.1 {
+			t.Errorf("expected level %v, got %v", float64(i)*0.1, levels[i])
+		}
+	}
+	levels = Levels(0.01, -3, 3)
+	if len(levels)!=601 || levels[600]!=3 {
+		t.Errorf("expected 601 levels from -3 to 3, got %d ending at %v", len(levels), levels[len(levels)-1])
+	}
+}
+
+func TestMarchCircle(t *testing.T) {
+	lats := axis(-10, 10, 0.5)
+	lngs := axis(-10, 10, 0.5)
+	v := make([][]float64, len(lats))
+	for i, lat := range lats {
+		v[i] = make([]float64, len(lngs))
+		for j, lng := range lngs {
+			v[i][j] = math.Hypot(lat, lng)
+		}
+	}
+
+	lines := march(lats, lngs, v, 5, 0)
+	if len(lines)!=1 {
+		t.Fatalf("expected a single line, got %d", len(lines))
+	}
+	line := lines[0]
+	if line[0]!=line[len(line)-1] {
+		t.Errorf("expected a closed line, got ends %v and %v", line[0], line[len(line)-1])
+	}
+	for _, p := range line {
+		testDiff("radius", math.Hypot(p.Lat, p.Lng), 5, 0.05, t)
+	}
+
+	// A line across the region is left open
+	for i := range v {
+		for j := range v[i] {
+			v[i][j] = lngs[j]
+		}
+	}
+	lines = march(lats, lngs, v, 2.25, 0)
+	if len(lines)!=1 || len(lines[0])!=len(lats) {
+		t.Fatalf("expected a single line of %d points, got %v", len(lats), lines)
+	}
+	for _, p := range lines[0] {
+		testDiff("longitude", p.Lng, 2.25, 1e-9, t)
+	}
+}
+
+func TestMarchWrap(t *testing.T) {
+	lats := axis(0, 2, 1)
+	lngs := axis(0, 2, 1)
+	v := [][]float64{{170, 175, 178}, {175, 179, -179}, {178, -178, -175}}
+	if lines := march(lats, lngs, v, 0, 360); len(lines)!=0 {
+		t.Errorf("expected no line across the wrap, got %v", lines)
+	}
+}
+
+func TestDeclinationContours(t *testing.T) {
+	w, err := wmm.LoadModel("../wmm/testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Region{MinLat: 20, MaxLat: 60, MinLng: -130, MaxLng: -60, Resolution: 1}
+	tt := wmm.DecimalYear(2022.5).ToTime()
+	lines, err := Contours(w, Declination, r, Levels(5, -20, 20), tt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var agonic int
+	for _, l := range lines {
+		if l.Level==0 {
+			agonic++
+		}
+		for _, p := range l.Points {
+			if p.Lat<r.MinLat || p.Lat>r.MaxLat || p.Lng<r.MinLng || p.Lng>r.MaxLng {
+				t.Errorf("point %v of level %v outside the region", p, l.Level)
+			}
+			mf, _ := w.MagneticField(egm96.NewLocationGeodetic(p.Lat, p.Lng, 0), tt)
+			testDiff("declination on contour", mf.D(), l.Level, 0.05, t)
+		}
+	}
+	if agonic==0 {
+		t.Errorf("expected the agonic line across North America")
+	}
+
+	var buf bytes.Buffer
+	if err = WriteGeoJSON(&buf, Declination, lines); err != nil {
+		t.Fatal(err)
+	}
+	var fc geoJSONFeatureCollection
+	if err = json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatalf("bad GeoJSON: %v", err)
+	}
+	var hasAgonic bool
+	for _, f := range fc.Features {
+		if f.Properties["kind"]=="Agonic" {
+			hasAgonic = true
+			if f.Properties["label"]!="Agonic line" {
+				t.Errorf("bad agonic line label %v", f.Properties["label"])
+			}
+		}
+	}
+	if !hasAgonic {
+		t.Errorf("expected an agonic line feature in the GeoJSON")
+	}
+
+	buf.Reset()
+	if err = WriteKML(&buf, "Declination 2022.5", Declination, lines); err != nil {
+		t.Fatal(err)
+	}
+	var doc kmlDoc
+	if err = xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("bad KML: %v", err)
+	}
+	if len(doc.Document.Placemarks)!=len(fc.Features) {
+		t.Errorf("expected %d placemarks, got %d", len(fc.Features), len(doc.Document.Placemarks))
+	}
+	if !strings.Contains(buf.String(), "<name>Agonic line</name>") {
+		t.Errorf("expected an agonic line placemark in the KML")
+	}
+}
@@ -0,0 +1,140 @@
+package contour
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Label returns the label of the contour lines of the component at the level,
+// e.g. "D 10 deg", or "Agonic line" for zero declination.
+func (c Component) Label(level float64) string {
+	if c.Name==Declination.Name && level==0 {
+		return "Agonic line"
+	}
+	return fmt.Sprintf("%s %g %s", c.Name, level, c.Units)
+}
+
+// byLevel groups the lines by level, in increasing order of level.
+func byLevel(lines []Line) (levels []float64, groups map[float64][]Line) {
+	groups = make(map[float64][]Line)
+	for _, l := range lines {
+		if _, ok := groups[l.Level]; !ok {
+			levels = append(levels, l.Level)
+		}
+		groups[l.Level] = append(groups[l.Level], l)
+	}
+	sort.Float64s(levels)
+	return levels, groups
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON writes the contour lines of the component as a GeoJSON
+// FeatureCollection, with one MultiLineString Feature for each level.
+//
+// Each Feature has the properties component, level, units, label and kind,
+// e.g. "Isogonic"; the agonic line has kind "Agonic".
+func WriteGeoJSON(w io.Writer, c Component, lines []Line) (err error) {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	levels, groups := byLevel(lines)
+	for _, level := range levels {
+		var coords [][][2]float64
+		for _, l := range groups[level] {
+			var line [][2]float64
+			for _, p := range l.Points {
+				line = append(line, [2]float64{p.Lng, p.Lat})
+			}
+			coords = append(coords, line)
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type: "Feature",
+			Properties: map[string]interface{}{
+				"component": c.Name,
+				"level":     level,
+				"units":     c.Units,
+				"label":     c.Label(level),
+				"kind":      c.kind(level),
+			},
+			Geometry: geoJSONGeometry{Type: "MultiLineString", Coordinates: coords},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(fc)
+}
+
+// kind returns the kind of contour line of the component at the level.
+func (c Component) kind(level float64) string {
+	if c.Name==Declination.Name && level==0 {
+		return "Agonic"
+	}
+	return c.Title
+}
+
+type kmlDoc struct {
+	XMLName  xml.Name `xml:"kml"`
+	NS       string   `xml:"xmlns,attr"`
+	Document kmlDocument
+}
+
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"Document"`
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	Lines       []kmlLineString `xml:"MultiGeometry>LineString"`
+}
+
+type kmlLineString struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes the contour lines of the component as a KML Document with
+// the input name, with one Placemark for each level, labelled as in WriteGeoJSON.
+func WriteKML(w io.Writer, name string, c Component, lines []Line) (err error) {
+	doc := kmlDoc{NS: "http://www.opengis.net/kml/2.2", Document: kmlDocument{Name: name}}
+	levels, groups := byLevel(lines)
+	for _, level := range levels {
+		pm := kmlPlacemark{Name: c.Label(level), Description: c.kind(level)}
+		for _, l := range groups[level] {
+			coords := make([]string, len(l.Points))
+			for i, p := range l.Points {
+				coords[i] = fmt.Sprintf("%.6f,%.6f,0", p.Lng, p.Lat)
+			}
+			pm.Lines = append(pm.Lines, kmlLineString{strings.Join(coords, " ")})
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err = enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,48 @@
+package egm96
+
+//go:generate go run gen_grid.go ../../assets/egm96/ww15mgh.grd grid_data.go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+// gridAssetName is the name of the NGA 15'x15' geoid height grid file.
+const gridAssetName = "ww15mgh.grd"
+
+// gridAsset holds the gzip-compressed NGA grid file embedded in the package.
+// It is set by grid_data.go, which is generated from the NGA file by go generate.
+var gridAsset []byte
+
+// getAsset returns the uncompressed contents of the embedded file with the
+// given name, or an error if it is not embedded in this build.
+func getAsset(name string) (data []byte, err error) {
+	if name!=gridAssetName {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	if len(gridAsset)==0 {
+		return nil, fmt.Errorf("EGM96 grid %s is not embedded in this build; "+
+			"load a grid with LoadEGM96Grid or ReadEGM96Grid", name)
+	}
+	return gunzip(gridAsset, name)
+}
+
+// gunzip returns the uncompressed contents of gzip-compressed data.
+func gunzip(data []byte, name string) (out []byte, err error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", name, err)
+	}
+	defer gz.Close()
+	if out, err = ioutil.ReadAll(gz); err != nil {
+		return nil, fmt.Errorf("read %s: %v", name, err)
+	}
+	return out, nil
+}
+
+// isGzip reports whether data starts with the gzip magic number.
+func isGzip(data []byte) bool {
+	return len(data)>=2 && data[0]==0x1f && data[1]==0x8b
+}
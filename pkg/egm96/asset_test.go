@@ -0,0 +1,164 @@
+package egm96
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testGrid is a 3x3 grid at 1º spacing from latitude 1º to -1º and longitude 0º to 2º.
+const testGrid = `-1.0 1.0 0.0 2.0 1.0 1.0
+ 1 2 3
+ 4 5 6
+ 7 8 9
+`
+
+// withGrid runs f with the grid read from data, and then restores the previous grid.
+func withGrid(t *testing.T, data []byte, f func()) {
+	gridMu.RLock()
+	g := egm96Grid
+	gridMu.RUnlock()
+	defer func() {
+		gridMu.Lock()
+		egm96Grid = g
+		gridMu.Unlock()
+	}()
+	if err := ReadEGM96Grid(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	f()
+}
+
+func checkTestGrid(t *testing.T) {
+	p, err := NewLocationGeodetic(0.1, 0.9, 0).NearestEGM96GridPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("nearest latitude", p.latitude/Deg, 0, eps, t)
+	testDiff("nearest longitude", p.longitude/Deg, 1, eps, t)
+	testDiff("nearest height", p.height, 5, eps, t)
+
+	h, err := NewLocationGeodetic(0.5, 0.5, 0).HeightAboveMSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("interpolated height", -h, 3, eps, t)
+}
+
+func TestReadEGM96Grid(t *testing.T) {
+	withGrid(t, []byte(testGrid), func() { checkTestGrid(t) })
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte(testGrid))
+	_ = w.Close()
+	withGrid(t, gz.Bytes(), func() { checkTestGrid(t) })
+}
+
+func TestLoadEGM96Grid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "egm96")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "grid.grd")
+	if err = ioutil.WriteFile(fn, []byte(testGrid), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	withGrid(t, []byte(testGrid), func() {
+		if err := LoadEGM96Grid(fn); err != nil {
+			t.Fatal(err)
+		}
+		checkTestGrid(t)
+	})
+
+	if err = LoadEGM96Grid(filepath.Join(dir, "missing.grd")); err == nil {
+		t.Errorf("expected an error loading a missing grid file")
+	}
+}
+
+func TestReadEGM96GridErrors(t *testing.T) {
+	gridMu.RLock()
+	before := egm96Grid
+	gridMu.RUnlock()
+
+	for _, bad := range []struct {
+		data         string
+		line, column int
+	}{
+		{"", 0, 0},
+		{"\n-1.0 1.0 0.0\n", 2, 0},
+		{"-1.0 1.0 0.0 2.0 x 1.0\n", 1, 18},
+		{"-1.0 1.0 0.0 2.0 1.0 0.0\n", 1, 22},
+		{"-1.0 1.0 0.0 2.0 1.0 1.0\n 1 2 3\n 4 five 6\n 7 8 9\n", 3, 4},
+		{"-1.0 1.0 0.0 2.0 1.0 1.0\n 1 2 3\n 4 5 6\n 7 8\n", 0, 0},
+		{"-1.0 1.0 0.0 2.0 1.0 1.0\n 1 2 3\n 4 5 6\n 7 8 9\n\t10\n", 5, 2},
+	} {
+		err := ReadEGM96Grid(strings.NewReader(bad.data))
+		var ge *GridError
+		if !errors.As(err, &ge) {
+			t.Errorf("expected a *GridError reading grid %q, got %v", bad.data, err)
+			continue
+		}
+		if ge.Line!=bad.line || ge.Column!=bad.column {
+			t.Errorf("expected an error at line %d column %d, got %v", bad.line, bad.column, err)
+		} else {
+			t.Logf("got expected error %v", err)
+		}
+	}
+
+	gridMu.RLock()
+	after := egm96Grid
+	gridMu.RUnlock()
+	if after!=before {
+		t.Errorf("a bad grid file replaced the grid")
+	}
+}
+
+func TestConcurrentGrid(t *testing.T) {
+	withGrid(t, []byte(testGrid), func() {
+		var wg sync.WaitGroup
+		for i:=0; i<8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j:=0; j<100; j++ {
+					if h, err := NewLocationGeodetic(0.5, 0.5, 0).HeightAboveMSL(); err != nil || h!= -3 {
+						t.Errorf("got height %v, error %v", h, err)
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if err := ReadEGM96Grid(strings.NewReader(testGrid)); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestGetAsset(t *testing.T) {
+	if _, err := getAsset("missing.grd"); err == nil {
+		t.Errorf("expected an error for a missing asset")
+	}
+	if len(gridAsset)==0 {
+		t.Fatal("EGM96 grid is not embedded; run go generate ./pkg/egm96")
+	}
+	data, err := getAsset(gridAssetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-90")) {
+		t.Errorf("unexpected embedded grid header %q", data[:40])
+	}
+}
@@ -0,0 +1,239 @@
+package egm96
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+const (
+	egm2008TileSize = 64  // Rows and columns of grid points in each tile read from the file
+	egm2008MaxTiles = 256 // Number of tiles cached in memory, 4MB in all
+	egm2008Markers  = 8   // Bytes of the Fortran record length markers around each row
+)
+
+// EGM2008 represents the geoid of the 2008 Earth Gravitational Model (EGM2008),
+// read lazily from one of NGA's geoid height grid files.
+//
+// NGA publishes EGM2008 geoid heights on 2.5'x2.5' and 1'x1' grids of about
+// 37 million and 233 million points.  Rather than holding these in memory as
+// for the EGM96 grid, an EGM2008 reads the file in tiles of 64x64 points as
+// they are needed, keeping the most recently used tiles, about 4MB, in memory.
+//
+// The geoid height is interpolated by the method set with SetInterpolation,
+// as for EGM96.
+//
+// An EGM2008 is safe for concurrent use by multiple goroutines.
+type EGM2008 struct {
+	g *geoidGrid
+	c io.Closer
+}
+
+// tileSource reads the geoid heights of a grid file lazily by tile.
+type tileSource struct {
+	r        io.ReaderAt
+	order    binary.ByteOrder
+	recLen   int64 // Bytes of each row record, including its markers
+	xn, yn   int   // Number of longitudes and latitudes
+	size     int   // Rows and columns of grid points in each tile
+	maxTiles int   // Number of tiles cached
+	mu       sync.Mutex
+	cache    map[int]*list.Element // Cached tiles by their index
+	lru      *list.List            // Cached tiles, most recently used first
+}
+
+// tile holds the geoid heights of a block of rows and columns of the grid.
+type tile struct {
+	key        int
+	i0, j0     int // First row and column of the tile
+	rows, cols int
+	h          []float32 // Geoid heights by row then column, m
+}
+
+// OpenEGM2008 opens the named EGM2008 geoid height grid file from NGA, e.g.
+// Und_min2.5x2.5_egm2008_isw=82_WGS84_TideFree_SE for the 2.5' grid.
+//
+// These are Fortran unformatted sequential files of 32-bit floating point
+// heights in meters, one record per row of latitude from 90ºN to 90ºS, each
+// row running east from 0º longitude.  The grid spacing and byte order,
+// little-endian "SE" or big-endian, are found from the record markers.
+//
+// The file is read lazily and stays open until Close is called.
+func OpenEGM2008(filename string) (e *EGM2008, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if e, err = readEGM2008(f, fi.Size(), filename); err != nil {
+		f.Close()
+		return nil, err
+	}
+	e.c = f
+	return e, nil
+}
+
+// NewEGM2008 returns an EGM2008 reading the geoid height grid provided by r,
+// which holds size bytes.  See OpenEGM2008 for the format.
+func NewEGM2008(r io.ReaderAt, size int64) (e *EGM2008, err error) {
+	return readEGM2008(r, size, "")
+}
+
+// readEGM2008 checks the record structure of the grid file and sets up the
+// grid to read it by tile.
+func readEGM2008(r io.ReaderAt, size int64, fn string) (e *EGM2008, err error) {
+	var head, tail [4]byte
+	if _, err = r.ReadAt(head[:], 0); err != nil {
+		return nil, fmt.Errorf("bad EGM2008 grid file %s: %v", fn, err)
+	}
+
+	// The record length must fit a whole number of rows into the file,
+	// and be repeated after the first record.
+	var (
+		order  binary.ByteOrder
+		recLen int64
+	)
+	for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		n := int64(o.Uint32(head[:]))
+		if n<8 || n%4!=0 || size%(n+egm2008Markers)!=0 {
+			continue
+		}
+		if _, err = r.ReadAt(tail[:], 4+n); err != nil {
+			return nil, fmt.Errorf("bad EGM2008 grid file %s: %v", fn, err)
+		}
+		if int64(o.Uint32(tail[:]))==n {
+			order, recLen = o, n+egm2008Markers
+			break
+		}
+	}
+	if order==nil {
+		return nil, fmt.Errorf("bad EGM2008 grid file %s: not a Fortran unformatted file of whole rows", fn)
+	}
+
+	xn := int((recLen-egm2008Markers)/4)
+	yn := int(size/recLen)
+	d := 360/float64(xn)
+	if yn<2 || math.Abs(float64(yn-1)*d-180)>1e-9 {
+		return nil, fmt.Errorf("bad EGM2008 grid file %s: %d rows of %d heights do not make a global grid",
+			fn, yn, xn)
+	}
+
+	return &EGM2008{g: &geoidGrid{
+		model: "EGM2008",
+		x0: 0, x1: 360-d, dx: d,
+		y0: 90, y1: -90, dy: -d,
+		xn: xn, yn: yn,
+		period: xn,
+		tiles: &tileSource{
+			r: r, order: order, recLen: recLen,
+			xn: xn, yn: yn,
+			size: egm2008TileSize, maxTiles: egm2008MaxTiles,
+			cache: make(map[int]*list.Element),
+			lru: list.New(),
+		},
+	}}, nil
+}
+
+// Close closes the grid file opened by OpenEGM2008.
+func (e *EGM2008) Close() (err error) {
+	if e.c==nil {
+		return nil
+	}
+	return e.c.Close()
+}
+
+// Resolution returns the spacing of the grid in degrees, e.g. 1/24 for the 2.5' grid.
+func (e *EGM2008) Resolution() (d float64) {
+	return e.g.dx
+}
+
+// GeoidHeight returns the height in meters of the EGM2008 geoid above the WGS84
+// reference ellipsoid at the input latitude and longitude in decimal degrees.
+func (e *EGM2008) GeoidHeight(latitude, longitude float64) (n float64, err error) {
+	return e.g.height(latitude, longitude, GetInterpolation())
+}
+
+// HeightAboveMSL returns the height above the EGM2008 geoid, i.e. above MSL,
+// of the input Location.
+func (e *EGM2008) HeightAboveMSL(l Location) (h float64, err error) {
+	n, err := e.GeoidHeight(l.latitude/Deg, l.longitude/Deg)
+	if err != nil {
+		return 0, err
+	}
+	return l.height - n, nil
+}
+
+// NewLocationMSL returns a Location given an input latitude, longitude, and
+// height above the EGM2008 geoid, i.e. above MSL, as for the package function
+// NewLocationMSL.
+func (e *EGM2008) NewLocationMSL(latitude, longitude, height float64) (loc Location, err error) {
+	n, err := e.GeoidHeight(latitude, longitude)
+	if err != nil {
+		return Location{}, err
+	}
+
+	return Location{
+		latitude: latitude*Deg,
+		longitude: longitude*Deg,
+		height: height + n,
+	}, nil
+}
+
+// at returns the height at grid row i and column j, reading its tile if it
+// is not cached, or an error if the tile could not be read.
+func (s *tileSource) at(i, j int) (h float64, err error) {
+	ti, tj := i/s.size, j/s.size
+	key := ti*((s.xn+s.size-1)/s.size)+tj
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var t *tile
+	if el, ok := s.cache[key]; ok {
+		s.lru.MoveToFront(el)
+		t = el.Value.(*tile)
+	} else {
+		if t, err = s.load(key, ti*s.size, tj*s.size); err != nil {
+			return 0, err
+		}
+		s.cache[key] = s.lru.PushFront(t)
+		if s.lru.Len()>s.maxTiles {
+			old := s.lru.Back()
+			s.lru.Remove(old)
+			delete(s.cache, old.Value.(*tile).key)
+		}
+	}
+	return float64(t.h[(i-t.i0)*t.cols+j-t.j0]), nil
+}
+
+// load reads the tile starting at row i0 and column j0 from the file.
+func (s *tileSource) load(key, i0, j0 int) (t *tile, err error) {
+	t = &tile{key: key, i0: i0, j0: j0, rows: s.size, cols: s.size}
+	if i0+t.rows>s.yn {
+		t.rows = s.yn-i0
+	}
+	if j0+t.cols>s.xn {
+		t.cols = s.xn-j0
+	}
+	t.h = make([]float32, t.rows*t.cols)
+
+	buf := make([]byte, 4*t.cols)
+	for i:=0; i<t.rows; i++ {
+		off := int64(i0+i)*s.recLen + 4 + int64(4*j0)
+		if n, err := s.r.ReadAt(buf, off); n<len(buf) {
+			return nil, fmt.Errorf("read EGM2008 grid row %d: %v", i0+i, err)
+		}
+		for j:=0; j<t.cols; j++ {
+			t.h[i*t.cols+j] = math.Float32frombits(s.order.Uint32(buf[4*j:]))
+		}
+	}
+	return t, nil
+}
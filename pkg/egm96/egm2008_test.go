@@ -0,0 +1,243 @@
+package egm96
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// The NGA EGM2008 2.5' grid file, which is not distributed with the package.
+var egm2008File = filepath.Join("..", "..", "assets", "egm2008", "Und_min2.5x2.5_egm2008_isw=82_WGS84_TideFree_SE")
+
+// egm2008Grid returns a global EGM2008-format grid file of smoothGeoid at spacing d degrees.
+func egm2008Grid(d float64, order binary.ByteOrder) []byte {
+	var b bytes.Buffer
+	xn := int(360/d+0.5)
+	for lat:=90.0; lat>= -90; lat -= d {
+		_ = binary.Write(&b, order, uint32(4*xn))
+		for j:=0; j<xn; j++ {
+			_ = binary.Write(&b, order, float32(smoothGeoid(lat, float64(j)*d)))
+		}
+		_ = binary.Write(&b, order, uint32(4*xn))
+	}
+	return b.Bytes()
+}
+
+// smallTiles makes the EGM2008 read tiles of 4x4 points, caching only 3 of them.
+func smallTiles(e *EGM2008) *EGM2008 {
+	e.g.tiles.size = 4
+	e.g.tiles.maxTiles = 3
+	return e
+}
+
+func TestEGM2008Synthetic(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := egm2008Grid(10, order)
+		e, err := NewEGM2008(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		smallTiles(e)
+		testDiff("resolution", e.Resolution(), 10, eps, t)
+		if e.g.xn!=36 || e.g.yn!=19 {
+			t.Errorf("expected a grid of 19×36 heights, got %d×%d", e.g.yn, e.g.xn)
+		}
+
+		for _, lat := range []float64{90, 40, 0, -90} {
+			for _, lng := range []float64{0, 10, 350, 360, -20} {
+				n, err := e.GeoidHeight(lat, lng)
+				if err != nil {
+					t.Fatal(err)
+				}
+				testDiff(fmt.Sprintf("%v height at %v,%v", order, lat, lng), n, smoothGeoid(lat, lng), 1e-5, t)
+			}
+		}
+	}
+}
+
+func TestEGM2008MatchesEGM96Grid(t *testing.T) {
+	data := egm2008Grid(10, binary.LittleEndian)
+	e, err := NewEGM2008(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallTiles(e)
+
+	withGrid(t, globalGrid(10), func() {
+		for _, m := range interpolations {
+			withInterpolation(t, m, func() {
+				for lat:= -88.3; lat<90; lat += 13.7 {
+					for lng:= -5.5; lng<370; lng += 17.3 {
+						n, err := e.GeoidHeight(lat, lng)
+						if err != nil {
+							t.Fatal(err)
+						}
+						h, err := NewLocationGeodetic(lat, lng, 0).HeightAboveMSL()
+						if err != nil {
+							t.Fatal(err)
+						}
+						testDiff(fmt.Sprintf("%v height at %v,%v", m, lat, lng), n, -h, 1e-4, t)
+					}
+				}
+			})
+		}
+	})
+
+	if l := len(e.g.tiles.cache); l>3 || e.g.tiles.lru.Len()!=l {
+		t.Errorf("expected at most 3 cached tiles, got %d", l)
+	}
+}
+
+func TestEGM2008MSL(t *testing.T) {
+	data := egm2008Grid(10, binary.LittleEndian)
+	e, err := NewEGM2008(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := e.NewLocationMSL(33.3, 243.3, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := e.HeightAboveMSL(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("height above MSL", h, 1500, eps, t)
+
+	if _, err = e.GeoidHeight(90.5, 0); err == nil {
+		t.Errorf("expected an error for a latitude outside of the grid")
+	}
+}
+
+func TestEGM2008Errors(t *testing.T) {
+	data := egm2008Grid(10, binary.BigEndian)
+	for _, bad := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", data[:len(data)-4]},
+		{"bad trailing marker", append(append([]byte{}, data[:4+144]...), 0, 0, 0, 0)},
+		{"not global", data[:3*(144+8)]},
+	} {
+		if _, err := NewEGM2008(bytes.NewReader(bad.data), int64(len(bad.data))); err == nil {
+			t.Errorf("expected an error reading a grid file %s", bad.name)
+		} else {
+			t.Logf("got expected error %v", err)
+		}
+	}
+
+	// A file which is shorter than it claims to be fails when the missing tiles are read
+	e, err := NewEGM2008(bytes.NewReader(data[:len(data)/2]), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallTiles(e)
+	if _, err = e.GeoidHeight(50, 10); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err = e.GeoidHeight(-50, 10); err == nil {
+		t.Errorf("expected an error reading a tile past the end of the file")
+	} else {
+		t.Logf("got expected error %v", err)
+	}
+
+	// A NaN height stored in the file is returned as it is, not as an error
+	binary.BigEndian.PutUint32(data[4:], math.Float32bits(float32(math.NaN())))
+	e, err = NewEGM2008(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withInterpolation(t, Nearest, func() {
+		if n, err := e.GeoidHeight(90, 0); err != nil || !math.IsNaN(n) {
+			t.Errorf("expected a NaN height and no error, got %v, %v", n, err)
+		}
+	})
+}
+
+func TestOpenEGM2008(t *testing.T) {
+	dir, err := ioutil.TempDir("", "egm2008")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "grid_SE")
+	if err = ioutil.WriteFile(fn, egm2008Grid(10, binary.LittleEndian), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := OpenEGM2008(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := e.GeoidHeight(20, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("height", n, smoothGeoid(20, 30), 1e-5, t)
+	if err = e.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = OpenEGM2008(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error opening a missing grid file")
+	}
+}
+
+func TestConcurrentEGM2008(t *testing.T) {
+	data := egm2008Grid(5, binary.LittleEndian)
+	e, err := NewEGM2008(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallTiles(e)
+
+	var wg sync.WaitGroup
+	for i:=0; i<8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j:=0; j<100; j++ {
+				lat, lng := float64(i*20-80)+0.3, float64(j*7)+0.6
+				n, err := e.GeoidHeight(lat, lng)
+				if err != nil || math.Abs(n-smoothGeoid(lat, lng))>0.5 {
+					t.Errorf("got height %v, error %v at %v,%v", n, err, lat, lng)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEGM2008File(t *testing.T) {
+	if _, err := os.Stat(egm2008File); err != nil {
+		t.Skipf("EGM2008 grid file %s is not available", egm2008File)
+	}
+	e, err := OpenEGM2008(egm2008File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if e.g.xn!=8640 || e.g.yn!=4321 {
+		t.Errorf("expected a grid of 4321×8640 heights, got %d×%d", e.g.yn, e.g.xn)
+	}
+
+	// EGM2008 and EGM96 agree to within a few meters away from high mountains
+	lats := []float64{38.628155, -14.621217, 46.874319, -23.617446, 38.625473, -0.466744}
+	lngs := []float64{269.779155, 305.021114, 102.448729, 133.874712, 359.999500, 0.002300}
+	hts  := []float64{-31.628, -2.969, -43.575, 15.871, 50.066, 17.329}
+	for i:=0; i<len(lats); i++ {
+		n, err := e.GeoidHeight(lats[i], lngs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDiff("EGM2008 against EGM96 height", n, hts[i], 5, t)
+	}
+}
@@ -12,17 +12,17 @@
 //
 // This package is based on the NGA-provided 15'x15' resolution grid encoding
 // the heights of the geopotential surface at each lat/long, and interpolates between grid
-// points using a bilinear interpolation.
+// points using bilinear interpolation by default, or by nearest neighbor,
+// bicubic or spline interpolation as chosen with SetInterpolation.
+// The grid is embedded in the package, or another grid file may be loaded
+// with LoadEGM96Grid or ReadEGM96Grid.
+// A HarmonicModel loaded from the NGA coefficient files instead sums the
+// spherical harmonic series to degree 360 at any point.
+//
+// The package also provides the EGM2008 geoid, read lazily from NGA's grid file.
 package egm96
 
-import (
-	"bufio"
-	"bytes"
-	"fmt"
-	"math"
-	"strconv"
-	"strings"
-)
+import "math"
 
 // Constants defining the WGS84 reference ellipsoid
 const (
@@ -56,6 +56,40 @@ func NewLocationGeodetic(latitude, longitude, height float64) (loc Location) {
 	}
 }
 
+// NewLocationSpherical returns a Location given an input latitude, longitude,
+// and distance from the center of the WGS84 sphere specified in the Spherical
+// (geocentric) system.
+//
+// Latitude and longitude are specified in decimal degrees and r in meters.
+//
+// Spherical coordinates are the variables φ',λ,r in the WMM paper.
+func NewLocationSpherical(latitude, longitude, r float64) (loc Location) {
+	sinPhi, cosPhi := math.Sincos(latitude*Deg)
+	p := r*cosPhi
+	z := r*sinPhi
+
+	// Iterate for the geodetic latitude and height, which converge to machine
+	// precision in a few steps for any location not too near the center of the Earth
+	var rc, h float64
+	phi := math.Atan2(z, p*(1-E2))
+	for i:=0; i<10; i++ {
+		sinPhi, cosPhi = math.Sincos(phi)
+		rc = A/math.Sqrt(1-E2*sinPhi*sinPhi)
+		if cosPhi>0.5 {
+			h = p/cosPhi - rc
+		} else {
+			h = z/sinPhi - rc*(1-E2)
+		}
+		phi = math.Atan2(z, p*(1-E2*rc/(rc+h)))
+	}
+
+	return Location{
+		latitude: phi,
+		longitude: longitude*Deg,
+		height: h,
+	}
+}
+
 // NewLocationMSL returns a Location given an input latitude, longitude, and height
 // above mean sea level.
 //
@@ -63,35 +97,17 @@ func NewLocationGeodetic(latitude, longitude, height float64) (loc Location) {
 // and the height is the height above mean sea level, NOT above the WGS84 Reference Ellipsoid.
 //
 // Latitude and longitude are specified in decimal degrees and height in meters.
+// The geoid height is interpolated by the method set with SetInterpolation.
 func NewLocationMSL(latitude, longitude, height float64) (loc Location, err error) {
-	if len(egm96Grid)==0 {
-		loadEGM96Grid()
-	}
-
-	nLng := int((longitude-egm96X0)/egm96DX) // Grid x just below desired x
-	nLat := int((latitude-egm96Y0)/egm96DY) // Grid y just below desired y
-
-	if nLng < 0 || nLng > egm96XN {
-		return Location{}, fmt.Errorf("requested longitude %4.2f lies outside of EGM96 longitude range %4.1f to %4.1f",
-			longitude, egm96X0, egm96X1)
-	}
-	if nLat < 0 || nLat > egm96YN {
-		return Location{}, fmt.Errorf("requested latitude %4.2f lies outside of EGM96 latitude range %4.1f to %4.1f",
-			latitude, egm96Y0, egm96Y1)
+	n, err := geoidHeight(latitude, longitude)
+	if err != nil {
+		return Location{}, err
 	}
 
-	x := (longitude-egm96X0)/egm96DX-float64(nLng)
-	y := (latitude-egm96Y0)/egm96DY-float64(nLat)
-	h00 := egm96Grid[nLat*egm96XN+nLng]
-	h10 := egm96Grid[nLat*egm96XN+nLng+1]
-	h01 := egm96Grid[(nLat+1)*egm96XN+nLng]
-	h11 := egm96Grid[(nLat+1)*egm96XN+nLng+1]
-
-
 	return Location{
 		latitude: latitude*Deg,
 		longitude: longitude*Deg,
-		height: height + ((1-x)*(1-y)*h00 + x*(1-y)*h10 + (1-x)*y*h01 + x*y*h11),
+		height: height + n,
 	}, nil
 }
 
@@ -127,45 +143,15 @@ func (l Location) Spherical() (phi, lambda, r float64) {
 // which corresponds to the height of MSL relative to the WGS84 reference ellipsoid.
 // It then subtracts this height from the total height above the WGS84 reference
 // ellipsoid at the input Location, giving the the height above MSL.
+// The geoid height is interpolated by the method set with SetInterpolation.
 func (l Location) HeightAboveMSL() (h float64, err error) {
-	if len(egm96Grid)==0 {
-		loadEGM96Grid()
-	}
-
-	lng := l.longitude/Deg
-	lat := l.latitude/Deg
-	nLng := int((lng-egm96X0)/egm96DX) // Grid x just below desired x
-	nLat := int((lat-egm96Y0)/egm96DY) // Grid y just below desired y
-
-	if nLng < 0 || nLng > egm96XN {
-		return 0, fmt.Errorf("requested longitude %4.2f lies outside of EGM96 longitude range %4.1f to %4.1f",
-			lng, egm96X0, egm96X1)
-	}
-	if nLat < 0 || nLat > egm96YN {
-		return 0, fmt.Errorf("requested latitude %4.2f lies outside of EGM96 latitude range %4.1f to %4.1f",
-			lat, egm96Y0, egm96Y1)
+	n, err := geoidHeight(l.latitude/Deg, l.longitude/Deg)
+	if err != nil {
+		return 0, err
 	}
-
-	x := (lng-egm96X0)/egm96DX-float64(nLng)
-	y := (lat-egm96Y0)/egm96DY-float64(nLat)
-	h00 := egm96Grid[nLat*egm96XN+nLng]
-	h10 := egm96Grid[nLat*egm96XN+nLng+1]
-	h01 := egm96Grid[(nLat+1)*egm96XN+nLng]
-	h11 := egm96Grid[(nLat+1)*egm96XN+nLng+1]
-
-	//TODO: implement spline interpolation to improve on bi-linear
-	h = l.height - ((1-x)*(1-y)*h00 + x*(1-y)*h10 + (1-x)*y*h01 + x*y*h11)
-
-	return h, err
+	return l.height - n, nil
 }
 
-var (
-	egm96X0, egm96X1, egm96DX float64
-	egm96Y0, egm96Y1, egm96DY float64
-	egm96XN, egm96YN int
-	egm96Grid []float64
-)
-
 // NearestEGM96GridPoint looks up the grid point nearest the desired location within the
 // 15'x15' resolution grid data for the EGM96 geoid model.
 //
@@ -174,99 +160,24 @@ var (
 //
 // Ignores any height value in the input Location.
 func (l Location) NearestEGM96GridPoint() (loc Location, err error) {
-	if len(egm96Grid)==0 {
-		loadEGM96Grid()
-	}
-
-	lng := l.longitude/Deg
-	for lng<0 {
-		lng += 360
-	}
-	for lng>=360 {
-		lng -= 360
+	g, err := getGrid()
+	if err != nil {
+		return Location{}, err
 	}
-	lat := l.latitude/Deg
-	nLng := int((lng-egm96X0)/egm96DX+0.5)
-	nLat := int((lat-egm96Y0)/egm96DY+0.5)
 
-	if nLng < 0 || nLng > egm96XN {
-		return Location{},
-			fmt.Errorf("requested longitude %4.2f lies outside of EGM96 longitude range %4.1f to %4.1f",
-				lng, egm96X0, egm96X1)
+	fy, fx, err := g.index(l.latitude/Deg, l.longitude/Deg)
+	if err != nil {
+		return Location{}, err
 	}
-	if nLat < 0 || nLat > egm96YN {
-		return Location{},
-			fmt.Errorf("requested latitude %4.2f lies outside of EGM96 latitude range %4.1f to %4.1f",
-				lat, egm96Y0, egm96Y1)
+	nLat, nLng := int(fy+0.5), int(fx+0.5)
+	h, err := g.at(nLat, nLng)
+	if err != nil {
+		return Location{}, err
 	}
 
 	return Location{
-		latitude:  (egm96Y0+egm96DY*float64(nLat))*Deg,
-		longitude: (egm96X0+egm96DX*float64(nLng))*Deg,
-		height:    egm96Grid[nLat*egm96XN+nLng],
+		latitude:  (g.y0+g.dy*float64(nLat))*Deg,
+		longitude: (g.x0+g.dx*float64(nLng))*Deg,
+		height:    h,
 	}, nil
 }
-
-func loadEGM96Grid() {
-	data, err := getAsset("ww15mgh.grd")
-	if err != nil {
-		panic(err)
-	}
-
-	var (
-		dat []string
-		v   float64
-		i   int
-	)
-
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	// Read and parse header
-	if !scanner.Scan() {
-		panic("Could not read header line from EGM96 grid file")
-	}
-	dat = strings.Fields(scanner.Text())
-	if egm96Y1, err = strconv.ParseFloat(dat[0], 64); err != nil {
-		panic("bad EGM96 grid file header for Y1")
-	}
-	if egm96Y0, err = strconv.ParseFloat(dat[1], 64); err != nil {
-		panic("bad EGM96 grid file header for Y0")
-	}
-	if egm96X0, err = strconv.ParseFloat(dat[2], 64); err != nil {
-		panic("bad EGM96 grid file header for X0")
-	}
-	if egm96X1, err = strconv.ParseFloat(dat[3], 64); err != nil {
-		panic("bad EGM96 grid file header for X1")
-	}
-	if egm96DX, err = strconv.ParseFloat(dat[4], 64); err != nil {
-		panic("bad EGM96 grid file header for DX")
-	}
-	if egm96DY, err = strconv.ParseFloat(dat[5], 64); err != nil {
-		panic("bad EGM96 grid file header for DY")
-	}
-
-	if egm96X1 < egm96X0 {
-		egm96DX *= -1
-	}
-	if egm96Y1 < egm96Y0 {
-		egm96DY *= -1
-	}
-	egm96XN = int((egm96X1-egm96X0)/egm96DX+0.5)+1 // Count the ends
-	egm96YN = int((egm96Y1-egm96Y0)/egm96DY+0.5)+1
-	egm96Grid = make([]float64, egm96XN*egm96YN)
-
-	// Read and parse data
-	i = 0
-	for scanner.Scan() {
-		for _, s := range strings.Fields(scanner.Text()) {
-			if v, err = strconv.ParseFloat(s, 64); err != nil {
-				panic("bad EGM96 grid data")
-			}
-			egm96Grid[i] = v
-			i++
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		panic(err)
-	}
-}
@@ -54,13 +54,28 @@ func TestNewLocationMSL(t *testing.T) {
 	}
 }
 
-func ExampleNearestEGM96GridPoint() {
+func TestNewLocationSpherical(t *testing.T) {
+	lats := []float64{0, 38, -12.25, 89.99, -90, 45, -60}
+	lngs := []float64{0, 270, 82.75, 10, 0, 100, 200}
+	hts  := []float64{0, 200, -1000, 99999, 5000, 1200000, -6000}
+
+	for i:=0; i<len(lats); i++ {
+		l := NewLocationGeodetic(lats[i], lngs[i], hts[i])
+		phi, lambda, r := l.Spherical()
+		ll := NewLocationSpherical(phi/Deg, lambda/Deg, r)
+		testDiff("latitude", ll.latitude/Deg, lats[i], 1e-9, t)
+		testDiff("longitude", ll.longitude/Deg, lngs[i], 1e-9, t)
+		testDiff("height", ll.height, hts[i], 1e-6, t)
+	}
+}
+
+func ExampleLocation_NearestEGM96GridPoint() {
 	p, _ := NewLocationGeodetic(-12.25,82.75,0).NearestEGM96GridPoint()
 	fmt.Printf("Lat: %4.2f, Lng: %4.2f, height: %5.3f", p.latitude/Deg, p.longitude/Deg, p.height)
 	// Output: Lat: -12.25, Lng: 82.75, height: -67.347
 }
 
-func ExampleConvertMSLToHeightAboveWGS84() {
+func ExampleLocation_HeightAboveMSL() {
 	h, _ := NewLocationGeodetic(-12.25,82.75,1000).HeightAboveMSL()
 	fmt.Printf("height Above Ellipsoid: %7.3f", h)
 	// Output: height Above Ellipsoid: 1067.347
@@ -0,0 +1,48 @@
+// +build ignore
+
+// gen_grid compresses the NGA geoid height grid file ww15mgh.grd and writes
+// it as a Go source file which embeds it in the egm96 package.
+//
+// Usage:
+//  go run gen_grid.go ww15mgh.grd grid_data.go
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+func main() {
+	if len(os.Args)!=3 {
+		log.Fatal("usage: go run gen_grid.go ww15mgh.grd grid_data.go")
+	}
+	data, err := ioutil.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var gz bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if _, err = w.Write(data); err != nil {
+		log.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by gen_grid.go from ww15mgh.grd. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package egm96\n\nfunc init() {\n\tgridAsset = []byte(\"")
+	for _, b := range gz.Bytes() {
+		fmt.Fprintf(&src, "\\x%02x", b)
+	}
+	fmt.Fprintf(&src, "\")\n}\n")
+
+	if err = ioutil.WriteFile(os.Args[2], src.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,243 @@
+package egm96
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// geoidGrid holds a regular grid of geoid heights, either in memory or read
+// lazily from a file.
+type geoidGrid struct {
+	model      string      // Name of the geoid model, for errors
+	x0, x1, dx float64     // First and last longitude and spacing, º
+	y0, y1, dy float64     // First and last latitude and spacing, º
+	xn, yn     int         // Number of longitudes and latitudes
+	period     int         // Number of distinct longitudes around the globe, or 0 if the grid is not global
+	h          []float64   // Geoid heights by latitude then longitude, m
+	tiles      *tileSource // Source of the geoid heights if h is nil
+}
+
+var (
+	gridMu       sync.RWMutex
+	egm96Grid    *geoidGrid // The grid used for all calculations, nil until loaded
+	embeddedOnce sync.Once
+	embeddedErr  error
+)
+
+// GridError reports a problem with the contents of a geoid grid file.
+type GridError struct {
+	File   string // The name of the grid file, if known
+	Line   int    // The line of the problem, counting from 1, or 0 for the whole file
+	Column int    // The column of the problem in the line, counting from 1, or 0 for the whole line
+	Msg    string
+}
+
+func (e *GridError) Error() string {
+	fn := e.File
+	if fn=="" {
+		fn = "input"
+	}
+	switch {
+	case e.Line==0:
+		return fmt.Sprintf("bad EGM96 grid file %s: %s", fn, e.Msg)
+	case e.Column==0:
+		return fmt.Sprintf("bad EGM96 grid file %s at line %d: %s", fn, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("bad EGM96 grid file %s at line %d, column %d: %s", fn, e.Line, e.Column, e.Msg)
+}
+
+// LoadEGM96Grid loads the geoid height grid from the named file, in the
+// format of the NGA grid file ww15mgh.grd, which may be gzip-compressed.
+// It replaces the grid embedded in the package for all later calculations.
+//
+// The file starts with a header line giving the south, north, west and east
+// bounds of the grid and the latitude and longitude spacing in degrees,
+//  -90.000000   90.000000     .000000  360.000000     .250000     .250000
+// followed by the geoid heights in meters, row by row from north to south
+// and from west to east within each row.
+//
+// A *GridError giving the line and column of the problem is returned if the
+// file is malformed, or if it does not hold exactly the number of heights
+// given by its header, in which case the grid in use is unchanged.
+// It is safe to call concurrently with any other function of the package.
+func LoadEGM96Grid(filename string) (err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return setEGM96Grid(data, filename)
+}
+
+// ReadEGM96Grid reads the geoid height grid provided by r, which may be
+// gzip-compressed.  It replaces the grid embedded in the package for all
+// later calculations.
+// See LoadEGM96Grid for the format and errors.
+func ReadEGM96Grid(r io.Reader) (err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return setEGM96Grid(data, "")
+}
+
+// getGrid returns the grid in use, loading the embedded grid exactly once
+// if no grid has been loaded yet.
+func getGrid() (g *geoidGrid, err error) {
+	gridMu.RLock()
+	g = egm96Grid
+	gridMu.RUnlock()
+	if g != nil {
+		return g, nil
+	}
+
+	embeddedOnce.Do(func() {
+		var (
+			data []byte
+			eg   *geoidGrid
+		)
+		if data, embeddedErr = getAsset(gridAssetName); embeddedErr != nil {
+			return
+		}
+		if eg, embeddedErr = parseEGM96Grid(data, gridAssetName); embeddedErr != nil {
+			return
+		}
+		gridMu.Lock()
+		if egm96Grid==nil {
+			egm96Grid = eg
+		}
+		gridMu.Unlock()
+	})
+
+	gridMu.RLock()
+	g = egm96Grid
+	gridMu.RUnlock()
+	if g==nil {
+		return nil, embeddedErr
+	}
+	return g, nil
+}
+
+// setEGM96Grid parses the grid file data and, if it is valid, makes it the
+// grid used for all calculations.
+func setEGM96Grid(data []byte, fn string) (err error) {
+	g, err := parseEGM96Grid(data, fn)
+	if err != nil {
+		return err
+	}
+	gridMu.Lock()
+	egm96Grid = g
+	gridMu.Unlock()
+	return nil
+}
+
+// field is a whitespace-separated field of a line and its column, counting from 1.
+type field struct {
+	s   string
+	col int
+}
+
+// fields splits the line around runs of spaces and tabs, as strings.Fields,
+// keeping the column of each field.
+func fields(line string) (fs []field) {
+	start := -1
+	for i:=0; i<=len(line); i++ {
+		if i==len(line) || line[i]==' ' || line[i]=='\t' || line[i]=='\r' {
+			if start>=0 {
+				fs = append(fs, field{line[start:i], start+1})
+				start = -1
+			}
+		} else if start<0 {
+			start = i
+		}
+	}
+	return fs
+}
+
+// parseEGM96Grid parses the grid file data, which may be gzip-compressed.
+func parseEGM96Grid(data []byte, fn string) (g *geoidGrid, err error) {
+	if isGzip(data) {
+		if data, err = gunzip(data, fn); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		line int
+		v    float64
+	)
+	g = &geoidGrid{model: "EGM96"}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	// Read and parse header
+	for len(fields(scanner.Text()))==0 {
+		if !scanner.Scan() {
+			if err = scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, &GridError{fn, 0, 0, "no header line"}
+		}
+		line++
+	}
+	header := fields(scanner.Text())
+	if len(header)!=6 {
+		return nil, &GridError{fn, line, 0,
+			fmt.Sprintf("header has %d values, expected south north west east dlat dlng", len(header))}
+	}
+	hdr := make([]float64, 6)
+	for i, f := range header {
+		if hdr[i], err = strconv.ParseFloat(f.s, 64); err != nil {
+			return nil, &GridError{fn, line, f.col, fmt.Sprintf("bad header value %q", f.s)}
+		}
+	}
+	g.y1, g.y0, g.x0, g.x1, g.dy, g.dx = hdr[0], hdr[1], hdr[2], hdr[3], hdr[4], hdr[5]
+	if g.dy<=0 {
+		return nil, &GridError{fn, line, header[4].col, fmt.Sprintf("latitude spacing %v is not positive", g.dy)}
+	}
+	if g.dx<=0 {
+		return nil, &GridError{fn, line, header[5].col, fmt.Sprintf("longitude spacing %v is not positive", g.dx)}
+	}
+	if g.x1 < g.x0 {
+		g.dx *= -1
+	}
+	if g.y1 < g.y0 {
+		g.dy *= -1
+	}
+	g.xn = int((g.x1-g.x0)/g.dx+0.5)+1 // Count the ends
+	g.yn = int((g.y1-g.y0)/g.dy+0.5)+1
+	if g.xn<2 || g.yn<2 {
+		return nil, &GridError{fn, line, 0, fmt.Sprintf("header gives a grid of %d×%d heights, need at least 2×2", g.yn, g.xn)}
+	}
+	if math.Abs(float64(g.xn-1)*g.dx)>=360-1e-9 {
+		g.period = g.xn-1 // The last longitude repeats the first
+	}
+	g.h = make([]float64, 0, g.xn*g.yn)
+
+	// Read and parse data
+	for scanner.Scan() {
+		line++
+		for _, f := range fields(scanner.Text()) {
+			if v, err = strconv.ParseFloat(f.s, 64); err != nil {
+				return nil, &GridError{fn, line, f.col, fmt.Sprintf("bad height %q", f.s)}
+			}
+			if len(g.h)==g.xn*g.yn {
+				return nil, &GridError{fn, line, f.col,
+					fmt.Sprintf("more than the %d×%d heights given by the header", g.yn, g.xn)}
+			}
+			g.h = append(g.h, v)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(g.h)!=g.xn*g.yn {
+		return nil, &GridError{fn, 0, 0,
+			fmt.Sprintf("%d heights, expected %d×%d from the header", len(g.h), g.yn, g.xn)}
+	}
+	return g, nil
+}
@@ -0,0 +1,259 @@
+package egm96
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Constants of the WGS84 normal gravity field used by NGA to compute EGM96 geoid heights
+const (
+	GM       = 0.3986004418e15 // Geocentric gravitational constant of WGS84 in m^3/s^2
+	gammaEq  = 9.7803253359    // Normal gravity at the equator in m/s^2
+	gammaK   = 0.00193185265246 // Somigliana's constant for normal gravity
+	zeroTerm = -0.53           // Zero degree term referring the undulation to the WGS84 ellipsoid in meters
+)
+
+// normalZonals are the fully normalized even zonal coefficients J2 to J10 of
+// the WGS84 normal gravity field, which are removed from the EGM96 coefficients
+// so that only the disturbing potential is summed.
+var normalZonals = []float64{0.108262982131e-2, -0.237091120053e-05, 0.608346498882e-8,
+	-0.142681087920e-10, 0.121439275882e-13}
+
+// HarmonicModel represents the EGM96 spherical harmonic coefficients of the
+// Earth's gravitational potential, as published by NGA with its f477 program.
+//
+// Where HeightAboveMSL interpolates the 15'x15' grid of geoid heights, a
+// HarmonicModel sums the series to its full degree of 360 at any point.
+// This is slower, about a millisecond per point, but does not depend on the
+// grid resolution.
+//
+// A HarmonicModel is safe for concurrent use by multiple goroutines.
+type HarmonicModel struct {
+	nMax   int
+	c, s   []float64 // Normalized C(n,m), S(n,m) less the normal field, by nmIndex(n,m)
+	cc, cs []float64 // Height anomaly to geoid undulation correction coefficients in cm, by nmIndex(n,m)
+	a, b   []float64 // Legendre recursion coefficients, by nmIndex(n,m)
+}
+
+// nmIndex returns the index of degree n and order m in the triangular coefficient arrays.
+func nmIndex(n, m int) int {
+	return n*(n+1)/2+m
+}
+
+// LoadHarmonicModel returns a new HarmonicModel loaded from the named EGM96
+// coefficient file, egm96 or EGM96 in NGA's distribution, and correction
+// coefficient file, CORCOEF.
+//
+// Each line of the coefficient file gives n, m and the fully normalized C(n,m)
+// and S(n,m), optionally followed by their standard deviations, e.g.
+//    2    0 -0.484165371736E-03  0.000000000000E+00  0.35610635E-10  0.00000000E+00
+// Each line of the correction file gives n, m and the coefficients in cm of
+// the correction from height anomaly to geoid undulation.
+// Fortran-style D exponents are accepted.
+func LoadHarmonicModel(coefFile, corrFile string) (hm *HarmonicModel, err error) {
+	coef, err := ioutil.ReadFile(coefFile)
+	if err != nil {
+		return nil, err
+	}
+	corr, err := ioutil.ReadFile(corrFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseHarmonicModel(coef, corr, coefFile, corrFile)
+}
+
+// ReadHarmonicModel returns a new HarmonicModel read from the EGM96
+// coefficients provided by coef and the correction coefficients provided by corr.
+// See LoadHarmonicModel for the formats.
+func ReadHarmonicModel(coef, corr io.Reader) (hm *HarmonicModel, err error) {
+	coefData, err := ioutil.ReadAll(coef)
+	if err != nil {
+		return nil, err
+	}
+	corrData, err := ioutil.ReadAll(corr)
+	if err != nil {
+		return nil, err
+	}
+	return parseHarmonicModel(coefData, corrData, "", "")
+}
+
+func parseHarmonicModel(coef, corr []byte, coefFile, corrFile string) (hm *HarmonicModel, err error) {
+	hm = new(HarmonicModel)
+	var c, s, cc, cs map[int]float64
+	var nc, ncc int
+	if c, s, nc, err = parseHarmonicCoefficients(coef, "EGM96 coefficient", coefFile); err != nil {
+		return nil, err
+	}
+	if nc<2 {
+		return nil, fmt.Errorf("no coefficients of degree 2 or more found in EGM96 coefficient file %s", coefFile)
+	}
+	if cc, cs, ncc, err = parseHarmonicCoefficients(corr, "EGM96 correction", corrFile); err != nil {
+		return nil, err
+	}
+
+	hm.nMax = nc
+	if ncc>hm.nMax {
+		hm.nMax = ncc
+	}
+	size := nmIndex(hm.nMax+1, 0)
+	hm.c, hm.s = make([]float64, size), make([]float64, size)
+	hm.cc, hm.cs = make([]float64, size), make([]float64, size)
+	for i, v := range c {
+		hm.c[i] = v
+	}
+	for i, v := range s {
+		hm.s[i] = v
+	}
+	for i, v := range cc {
+		hm.cc[i] = v
+	}
+	for i, v := range cs {
+		hm.cs[i] = v
+	}
+	for i, j := range normalZonals {
+		n := 2*i+2
+		if n<=hm.nMax {
+			hm.c[nmIndex(n, 0)] += j/math.Sqrt(float64(2*n+1))
+		}
+	}
+
+	hm.a, hm.b = make([]float64, size), make([]float64, size)
+	for n:=2; n<=hm.nMax; n++ {
+		for m:=0; m<=n-2; m++ {
+			nm := float64((n-m)*(n+m))
+			hm.a[nmIndex(n, m)] = math.Sqrt(float64((2*n-1)*(2*n+1))/nm)
+			hm.b[nmIndex(n, m)] = math.Sqrt(float64((2*n+1)*(n+m-1)*(n-m-1))/(nm*float64(2*n-3)))
+		}
+	}
+	return hm, nil
+}
+
+// parseHarmonicCoefficients parses lines of n, m, C(n,m), S(n,m), returning
+// the coefficients by nmIndex(n,m) and the highest degree found.
+func parseHarmonicCoefficients(data []byte, kind, fn string) (c, s map[int]float64, nMax int, err error) {
+	var (
+		n, m, line int
+		cnm, snm   float64
+	)
+	c, s = make(map[int]float64), make(map[int]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line++
+		f := strings.Fields(strings.NewReplacer("D", "E", "d", "e").Replace(scanner.Text()))
+		if len(f)==0 {
+			continue
+		}
+		if len(f)<4 {
+			return nil, nil, 0, fmt.Errorf("line %d of %s file %s has %d values, expected n m C S", line, kind, fn, len(f))
+		}
+		if n, err = strconv.Atoi(f[0]); err != nil {
+			return nil, nil, 0, fmt.Errorf("bad n value at line %d of %s file %s", line, kind, fn)
+		}
+		if m, err = strconv.Atoi(f[1]); err != nil {
+			return nil, nil, 0, fmt.Errorf("bad m value at line %d of %s file %s", line, kind, fn)
+		}
+		if n<0 || m<0 || m>n {
+			return nil, nil, 0, fmt.Errorf("bad n, m = (%d,%d) at line %d of %s file %s", n, m, line, kind, fn)
+		}
+		if cnm, err = strconv.ParseFloat(f[2], 64); err != nil {
+			return nil, nil, 0, fmt.Errorf("bad C value at line %d of %s file %s", line, kind, fn)
+		}
+		if snm, err = strconv.ParseFloat(f[3], 64); err != nil {
+			return nil, nil, 0, fmt.Errorf("bad S value at line %d of %s file %s", line, kind, fn)
+		}
+		c[nmIndex(n, m)], s[nmIndex(n, m)] = cnm, snm
+		if n>nMax {
+			nMax = n
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	return c, s, nMax, nil
+}
+
+// MaxDegree returns the maximum degree (and order) n of the coefficients of
+// the HarmonicModel, e.g. 360 for EGM96.
+func (hm *HarmonicModel) MaxDegree() (n int) {
+	return hm.nMax
+}
+
+// legendre returns the fully normalized associated Legendre functions P(n,m)
+// of sin(φ') = t, cos(φ') = u by nmIndex(n,m), up to the degree of the model.
+//
+// They are computed by the standard forward column recursion, which is stable
+// to well beyond degree 360.  Near the poles the sectoral functions of high
+// order underflow to zero, where their contributions are negligible.
+func (hm *HarmonicModel) legendre(t, u float64) (p []float64) {
+	p = make([]float64, nmIndex(hm.nMax+1, 0))
+	p[0] = 1
+	pmm := 1.0
+	for m:=0; m<=hm.nMax; m++ {
+		switch m {
+		case 0:
+		case 1:
+			pmm = math.Sqrt(3)*u
+		default:
+			pmm *= math.Sqrt(float64(2*m+1)/float64(2*m))*u
+		}
+		p[nmIndex(m, m)] = pmm
+		if m==hm.nMax {
+			break
+		}
+		p[nmIndex(m+1, m)] = math.Sqrt(float64(2*m+3))*t*pmm
+		for n:=m+2; n<=hm.nMax; n++ {
+			i := nmIndex(n, m)
+			p[i] = hm.a[i]*t*p[nmIndex(n-1, m)] - hm.b[i]*p[nmIndex(n-2, m)]
+		}
+	}
+	return p
+}
+
+// GeoidHeight returns the height in meters of the EGM96 geoid above the WGS84
+// reference ellipsoid at the input latitude and longitude in decimal degrees,
+// summing the spherical harmonic series to the full degree of the model.
+func (hm *HarmonicModel) GeoidHeight(latitude, longitude float64) (n float64) {
+	phi, lambda, r := NewLocationGeodetic(latitude, longitude, 0).Spherical()
+	sinLat := math.Sin(latitude*Deg)
+	gamma := gammaEq*(1+gammaK*sinLat*sinLat)/math.Sqrt(1-E2*sinLat*sinLat)
+
+	t, u := math.Sincos(phi)
+	p := hm.legendre(t, u)
+
+	sinML, cosML := make([]float64, hm.nMax+1), make([]float64, hm.nMax+1)
+	for m:=0; m<=hm.nMax; m++ {
+		sinML[m], cosML[m] = math.Sincos(float64(m)*lambda)
+	}
+
+	// The disturbing potential from degree 2 and the correction from degree 0
+	var sum, corr float64
+	ar := A/r
+	arn := ar
+	for nn:=0; nn<=hm.nMax; nn++ {
+		var sn, cn float64
+		for m:=0; m<=nn; m++ {
+			i := nmIndex(nn, m)
+			sn += p[i]*(hm.c[i]*cosML[m] + hm.s[i]*sinML[m])
+			cn += p[i]*(hm.cc[i]*cosML[m] + hm.cs[i]*sinML[m])
+		}
+		corr += cn
+		if nn>=2 {
+			arn *= ar
+			sum += arn*sn
+		}
+	}
+
+	return sum*GM/(gamma*r) + corr/100 + zeroTerm
+}
+
+// HeightAboveMSL returns the height above the EGM96 geoid, i.e. above MSL, of
+// the input Location, with the geoid height summed from the HarmonicModel.
+func (hm *HarmonicModel) HeightAboveMSL(l Location) (h float64) {
+	return l.height - hm.GeoidHeight(l.latitude/Deg, l.longitude/Deg)
+}
@@ -0,0 +1,259 @@
+package egm96
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/polynomial"
+)
+
+// The NGA EGM96 coefficient files, which are not distributed with the package.
+var (
+	harmonicCoefFile = filepath.Join("..", "..", "assets", "egm96", "EGM96")
+	harmonicCorrFile = filepath.Join("..", "..", "assets", "egm96", "CORCOEF")
+)
+
+// f477Nodes are geoid undulations in meters computed by NGA's f477 program from
+// the EGM96 coefficients at nodes of the 15'x15' grid, as given in ww15mgh.grd.
+var f477Nodes = []struct {
+	lat, lng, n float64
+}{
+	{90, 0, 13.606},
+	{64.25, 212.75, 13.282},
+	{46.75, 102.5, -43.517},
+	{38.75, 269.75, -31.824},
+	{27.75, 86.75, -31.649},
+	{4.75, 79.75, -106.1},
+	{0, 0, 17.162},
+	{-8.25, 147.25, 85.391},
+	{-14.5, 305, -3.256},
+	{-23.5, 134, 17.472},
+	{-52, 360, 26.391},
+	{-90, 180, -29.534},
+}
+
+// normalFieldCoefficients returns coefficient file lines of degree 2 to 10
+// holding only the WGS84 normal gravity field.
+func normalFieldCoefficients() string {
+	var b strings.Builder
+	for i, j := range normalZonals {
+		n := 2*i+2
+		fmt.Fprintf(&b, "%4d %4d %22.15E %22.15E\n", n, 0, -j/math.Sqrt(float64(2*n+1)), 0.0)
+	}
+	return b.String()
+}
+
+func TestLegendre(t *testing.T) {
+	hm, err := ReadHarmonicModel(strings.NewReader("360 360 0 0\n"), strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hm.MaxDegree()!=360 {
+		t.Errorf("expected degree 360, got %d", hm.MaxDegree())
+	}
+
+	for _, lat := range []float64{0, 17.3, -45, 71.9, 89.75, -90} {
+		s, c := math.Sincos(lat*Deg)
+		p := hm.legendre(s, c)
+
+		testDiff("P(1,0)", p[nmIndex(1, 0)], math.Sqrt(3)*s, 1e-12, t)
+		testDiff("P(2,0)", p[nmIndex(2, 0)], math.Sqrt(5)*(3*s*s-1)/2, 1e-12, t)
+		testDiff("P(2,2)", p[nmIndex(2, 2)], math.Sqrt(15)*c*c/2, 1e-12, t)
+		testDiff("P(3,1)", p[nmIndex(3, 1)], math.Sqrt(42)*c*(5*s*s-1)/4, 1e-12, t)
+
+		// By the addition theorem the squares of each degree sum to 2n+1
+		for _, n := range []int{10, 100, 250, 360} {
+			var sum float64
+			for m:=0; m<=n; m++ {
+				sum += p[nmIndex(n, m)]*p[nmIndex(n, m)]
+			}
+			testDiff(fmt.Sprintf("sum of P(%d,m)² at %v", n, lat), sum/float64(2*n+1), 1, 1e-10, t)
+		}
+	}
+}
+
+func TestHarmonicModelSynthetic(t *testing.T) {
+	// The normal field alone gives only the zero degree term
+	hm, err := ReadHarmonicModel(strings.NewReader(normalFieldCoefficients()), strings.NewReader("0 0 0 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][2]float64{{0, 0}, {45, 100}, {-89, 270}} {
+		testDiff("normal field undulation", hm.GeoidHeight(p[0], p[1]), zeroTerm, 1e-9, t)
+	}
+
+	// A single C(2,2) and a constant correction of 1m
+	hm, err = ReadHarmonicModel(strings.NewReader(normalFieldCoefficients()+"2 2 1D-6 0D0\n"),
+		strings.NewReader("0 0 100 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][2]float64{{0, 0}, {30, 60}, {-52.5, 200}} {
+		phi, lambda, r := NewLocationGeodetic(p[0], p[1], 0).Spherical()
+		sinLat := math.Sin(p[0]*Deg)
+		gamma := gammaEq*(1+gammaK*sinLat*sinLat)/math.Sqrt(1-E2*sinLat*sinLat)
+		c := math.Cos(phi)
+		n := GM/(gamma*r)*(A/r)*(A/r)*1e-6*math.Sqrt(15)*c*c/2*math.Cos(2*lambda) + 1 + zeroTerm
+		testDiff(fmt.Sprintf("C(2,2) undulation at %v", p), hm.GeoidHeight(p[0], p[1]), n, 1e-9, t)
+
+		l := NewLocationGeodetic(p[0], p[1], 100)
+		testDiff("harmonic height above MSL", hm.HeightAboveMSL(l), 100-n, 1e-9, t)
+	}
+}
+
+func TestReadHarmonicModelErrors(t *testing.T) {
+	for _, bad := range []struct {
+		coef, corr string
+	}{
+		{"", "0 0 0 0\n"},
+		{"2 0 1 0\n3 1 x 0\n", "0 0 0 0\n"},
+		{"2 3 1 0\n", "0 0 0 0\n"},
+		{"2 0 1\n", "0 0 0 0\n"},
+		{"2 0 1 0\n", "0 0 0 y\n"},
+	} {
+		if _, err := ReadHarmonicModel(strings.NewReader(bad.coef), strings.NewReader(bad.corr)); err == nil {
+			t.Errorf("expected an error reading coefficients %q, corrections %q", bad.coef, bad.corr)
+		} else {
+			t.Logf("got expected error %v", err)
+		}
+	}
+}
+
+func TestHarmonicModelAgainstLegendreFunction(t *testing.T) {
+	// Pseudo-random coefficients to degree 12, summed with the associated Legendre
+	// functions of package polynomial, which are independent of the recursion
+	const nMax = 12
+	c, s := make(map[int]float64), make(map[int]float64)
+	var b strings.Builder
+	b.WriteString(normalFieldCoefficients())
+	for n:=2; n<=nMax; n++ {
+		for m:=0; m<=n; m++ {
+			c[nmIndex(n, m)] = 1e-6*math.Sin(float64(7*n+3*m))/float64(n*n)
+			if m>0 {
+				s[nmIndex(n, m)] = 1e-6*math.Cos(float64(5*n-2*m))/float64(n*n)
+			}
+			fmt.Fprintf(&b, "%d %d %.15e %.15e\n", n, m, c[nmIndex(n, m)], s[nmIndex(n, m)])
+		}
+	}
+	for i, j := range normalZonals {
+		c[nmIndex(2*i+2, 0)] += j/math.Sqrt(float64(4*i+5))
+	}
+	hm, err := ReadHarmonicModel(strings.NewReader(b.String()), strings.NewReader("0 0 0 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {37.2, 121.7}, {-63.9, 288.4}, {88.1, 15}} {
+		phi, lambda, r := NewLocationGeodetic(p[0], p[1], 0).Spherical()
+		sinLat := math.Sin(p[0]*Deg)
+		gamma := gammaEq*(1+gammaK*sinLat*sinLat)/math.Sqrt(1-E2*sinLat*sinLat)
+
+		var sum float64
+		for n:=2; n<=nMax; n++ {
+			for m:=0; m<=n; m++ {
+				norm := float64(2*(2*n+1))/polynomial.FactorialRatioFloat(n+m, n-m)
+				if m==0 {
+					norm /= 2
+				}
+				pnm := math.Sqrt(norm)*polynomial.LegendreFunction(n, m, math.Sin(phi))
+				sm, cm := math.Sincos(float64(m)*lambda)
+				sum += math.Pow(A/r, float64(n))*pnm*(c[nmIndex(n, m)]*cm + s[nmIndex(n, m)]*sm)
+			}
+		}
+		n := sum*GM/(gamma*r) + zeroTerm
+		testDiff(fmt.Sprintf("undulation at %v", p), hm.GeoidHeight(p[0], p[1]), n, 1e-6, t)
+	}
+}
+
+func TestHarmonicModelSyntheticGrid(t *testing.T) {
+	// A grid of the series at 15º nodes is interpolated back to the series at the nodes
+	hm, err := ReadHarmonicModel(strings.NewReader(normalFieldCoefficients()+"2 1 3D-7 -2D-7\n5 3 1D-7 4D-8\n"),
+		strings.NewReader("0 0 0 0\n3 2 20 -10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	b.WriteString("-90.0 90.0 0.0 360.0 15.0 15.0\n")
+	for lat:=90.0; lat>= -90; lat -= 15 {
+		for lng:=0.0; lng<=360; lng += 15 {
+			fmt.Fprintf(&b, " %.9f", hm.GeoidHeight(lat, lng))
+		}
+		b.WriteString("\n")
+	}
+
+	withGrid(t, []byte(b.String()), func() {
+		for _, p := range [][2]float64{{75, 30}, {0, 0}, {-45, 345}, {-15, 360}} {
+			h, err := NewLocationGeodetic(p[0], p[1], 0).HeightAboveMSL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDiff(fmt.Sprintf("grid and harmonic undulation at %v", p), -h, hm.GeoidHeight(p[0], p[1]), 1e-6, t)
+		}
+	})
+}
+
+func TestGridAgainstF477(t *testing.T) {
+	if _, err := getGrid(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range interpolations {
+		withInterpolation(t, m, func() {
+			for _, p := range f477Nodes {
+				h, err := NewLocationGeodetic(p.lat, p.lng, 0).HeightAboveMSL()
+				if err != nil {
+					t.Fatal(err)
+				}
+				testDiff(fmt.Sprintf("%v grid undulation at %v,%v", m, p.lat, p.lng), -h, p.n, 1e-9, t)
+			}
+		})
+	}
+}
+
+func TestHarmonicModelAgainstGrid(t *testing.T) {
+	for _, fn := range []string{harmonicCoefFile, harmonicCorrFile} {
+		if _, err := os.Stat(fn); err != nil {
+			t.Skipf("EGM96 coefficient file %s is not available", fn)
+		}
+	}
+	g, err := getGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hm, err := LoadHarmonicModel(harmonicCoefFile, harmonicCorrFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hm.MaxDegree()!=360 {
+		t.Errorf("expected degree 360, got %d", hm.MaxDegree())
+	}
+
+	// NGA computed the grid from the same series and rounded it to the millimeter
+	for _, p := range f477Nodes {
+		testDiff(fmt.Sprintf("undulation at %v,%v", p.lat, p.lng), hm.GeoidHeight(p.lat, p.lng), p.n, 0.002, t)
+	}
+	for i:=0; i<g.yn; i += 37 {
+		for j:=0; j<g.xn; j += 53 {
+			lat, lng := g.y0+float64(i)*g.dy, g.x0+float64(j)*g.dx
+			n, err := g.at(i, j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDiff(fmt.Sprintf("undulation at %v,%v", lat, lng), hm.GeoidHeight(lat, lng), n, 0.002, t)
+		}
+	}
+}
+
+func BenchmarkHarmonicModel(b *testing.B) {
+	hm, err := ReadHarmonicModel(strings.NewReader("360 360 1e-9 1e-9\n"), strings.NewReader("0 0 0 0\n"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i:=0; i<b.N; i++ {
+		hm.GeoidHeight(38.628155, 269.779155)
+	}
+}
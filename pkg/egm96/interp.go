@@ -0,0 +1,239 @@
+package egm96
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// Interpolation is a method of interpolating the geoid height between the
+// points of the grid.
+type Interpolation int32
+
+// Interpolation methods
+const (
+	Nearest  Interpolation = iota // The height at the nearest grid point
+	Bilinear                      // Bilinear interpolation between the 2x2 surrounding grid points
+	Bicubic                       // Natural cubic splines through the 4x4 surrounding grid points, as in NGA's interp program
+	Spline                        // Natural cubic splines through the 6x6 surrounding grid points
+)
+
+func (m Interpolation) String() string {
+	switch m {
+	case Nearest:
+		return "nearest"
+	case Bilinear:
+		return "bilinear"
+	case Bicubic:
+		return "bicubic"
+	case Spline:
+		return "spline"
+	}
+	return fmt.Sprintf("Interpolation(%d)", int32(m))
+}
+
+// window returns the number of grid points on each side of the square used
+// by the spline interpolation methods.
+func (m Interpolation) window() int {
+	if m==Spline {
+		return 6
+	}
+	return 4
+}
+
+var interpolation = int32(Bilinear)
+
+// SetInterpolation sets the method used by HeightAboveMSL and NewLocationMSL
+// to interpolate the geoid height between grid points.
+// The default is Bilinear.
+//
+// The spline methods follow the curvature of the geoid between grid points,
+// where bilinear interpolation cuts across it.
+// It is safe to call concurrently with any other function of the package.
+func SetInterpolation(m Interpolation) (err error) {
+	if m<Nearest || m>Spline {
+		return fmt.Errorf("unknown EGM96 interpolation method %v", m)
+	}
+	atomic.StoreInt32(&interpolation, int32(m))
+	return nil
+}
+
+// GetInterpolation returns the method used to interpolate the geoid height
+// between grid points.
+func GetInterpolation() (m Interpolation) {
+	return Interpolation(atomic.LoadInt32(&interpolation))
+}
+
+// geoidHeight returns the geoid height at the input latitude and longitude,
+// in degrees, of the grid in use, by the chosen interpolation method.
+func geoidHeight(lat, lng float64) (h float64, err error) {
+	g, err := getGrid()
+	if err != nil {
+		return 0, err
+	}
+	return g.height(lat, lng, GetInterpolation())
+}
+
+// wraps reports whether the grid covers all longitudes.
+func (g *geoidGrid) wraps() bool {
+	return g.period>0
+}
+
+// index returns the fractional grid indices of the input latitude and
+// longitude, in degrees, or an error if they are outside of the grid.
+func (g *geoidGrid) index(lat, lng float64) (fy, fx float64, err error) {
+	if g.wraps() {
+		lng = g.x0 + math.Mod(math.Mod(lng-g.x0, 360)+360, 360)
+	}
+	fx = (lng-g.x0)/g.dx
+	fy = (lat-g.y0)/g.dy
+	const tol = 1e-9
+	if !g.wraps() && (fx < -tol || fx > float64(g.xn-1)+tol) {
+		return 0, 0, fmt.Errorf("requested longitude %4.2f lies outside of %s longitude range %4.1f to %4.1f",
+			lng, g.model, g.x0, g.x1)
+	}
+	if fy < -tol || fy > float64(g.yn-1)+tol {
+		return 0, 0, fmt.Errorf("requested latitude %4.2f lies outside of %s latitude range %4.1f to %4.1f",
+			lat, g.model, g.y0, g.y1)
+	}
+	if g.wraps() {
+		return math.Max(0, math.Min(fy, float64(g.yn-1))), fx, nil
+	}
+	return math.Max(0, math.Min(fy, float64(g.yn-1))), math.Max(0, math.Min(fx, float64(g.xn-1))), nil
+}
+
+// at returns the height at grid row i and column j, wrapping the column
+// around the globe if the grid covers all longitudes, or clamping it otherwise.
+// An error is returned only if the height could not be read from a file.
+func (g *geoidGrid) at(i, j int) (h float64, err error) {
+	if g.wraps() {
+		j = (j%g.period+g.period)%g.period
+	} else if j<0 {
+		j = 0
+	} else if j>=g.xn {
+		j = g.xn-1
+	}
+	if g.tiles != nil {
+		return g.tiles.at(i, j)
+	}
+	return g.h[i*g.xn+j], nil
+}
+
+// height returns the geoid height at the input latitude and longitude, in
+// degrees, by the interpolation method m.
+func (g *geoidGrid) height(lat, lng float64, m Interpolation) (h float64, err error) {
+	fy, fx, err := g.index(lat, lng)
+	if err != nil {
+		return 0, err
+	}
+
+	switch m {
+	case Nearest:
+		return g.at(int(fy+0.5), int(fx+0.5))
+	case Bilinear:
+		i := int(math.Min(fy, float64(g.yn-2)))
+		j := int(fx)
+		if !g.wraps() && j>g.xn-2 {
+			j = g.xn-2
+		}
+		y, x := fy-float64(i), fx-float64(j)
+		var hs [2][2]float64
+		for di := range hs {
+			for dj := range hs[di] {
+				if hs[di][dj], err = g.at(i+di, j+dj); err != nil {
+					return 0, err
+				}
+			}
+		}
+		return (1-x)*(1-y)*hs[0][0] + x*(1-y)*hs[0][1] + (1-x)*y*hs[1][0] + x*y*hs[1][1], nil
+	case Bicubic, Spline:
+		return g.spline(fy, fx, m.window())
+	}
+	return 0, fmt.Errorf("unknown EGM96 interpolation method %v", m)
+}
+
+// spline interpolates the height at the fractional grid indices fy, fx by
+// natural cubic splines along each row of a window of w×w grid points
+// around them, and then along the column of the results.
+// The window is moved inside the grid at the poles, and wraps around in
+// longitude if the grid covers all longitudes.
+func (g *geoidGrid) spline(fy, fx float64, w int) (h float64, err error) {
+	i0 := windowStart(fy, w, g.yn, false)
+	j0 := windowStart(fx, w, g.xn, g.wraps())
+	ny := w
+	if ny>g.yn {
+		ny = g.yn
+	}
+	nx := w
+	if nx>g.xn && !g.wraps() {
+		nx = g.xn
+	}
+
+	col := make([]float64, ny)
+	row := make([]float64, nx)
+	for i := range col {
+		for j := range row {
+			if row[j], err = g.at(i0+i, j0+j); err != nil {
+				return 0, err
+			}
+		}
+		col[i] = naturalSpline(row, fx-float64(j0))
+	}
+	return naturalSpline(col, fy-float64(i0)), nil
+}
+
+// windowStart returns the index of the first of w points centered on the
+// fractional index f, kept within the n points available unless wrap is set.
+func windowStart(f float64, w, n int, wrap bool) (i0 int) {
+	i0 = int(math.Floor(f)) - (w/2-1)
+	if wrap {
+		return i0
+	}
+	if i0+w>n {
+		i0 = n-w
+	}
+	if i0<0 {
+		i0 = 0
+	}
+	return i0
+}
+
+// naturalSpline returns the value at the fractional index t of the natural
+// cubic spline through the equally spaced values y.
+func naturalSpline(y []float64, t float64) (v float64) {
+	n := len(y)
+	switch {
+	case n==1:
+		return y[0]
+	case n==2:
+		return y[0] + t*(y[1]-y[0])
+	}
+
+	// Solve the tridiagonal system for the second derivatives, with zero
+	// second derivative at both ends, by the Thomas algorithm.
+	m := make([]float64, n)
+	c := make([]float64, n)
+	for i:=1; i<n-1; i++ {
+		d := 6*(y[i+1]-2*y[i]+y[i-1])
+		b := 4.0
+		if i>1 {
+			b -= c[i-1]
+			d -= m[i-1]
+		}
+		c[i] = 1/b
+		m[i] = d/b
+	}
+	for i:=n-3; i>=1; i-- {
+		m[i] -= c[i]*m[i+1]
+	}
+
+	k := int(math.Floor(t))
+	if k<0 {
+		k = 0
+	} else if k>n-2 {
+		k = n-2
+	}
+	a := t-float64(k)
+	b := 1-a
+	return b*y[k] + a*y[k+1] + ((b*b*b-b)*m[k] + (a*a*a-a)*m[k+1])/6
+}
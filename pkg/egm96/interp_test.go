@@ -0,0 +1,196 @@
+package egm96
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+var interpolations = []Interpolation{Nearest, Bilinear, Bicubic, Spline}
+
+// withInterpolation runs f with the interpolation method m, and then restores the previous method.
+func withInterpolation(t *testing.T, m Interpolation, f func()) {
+	old := GetInterpolation()
+	defer func() { _ = SetInterpolation(old) }()
+	if err := SetInterpolation(m); err != nil {
+		t.Fatal(err)
+	}
+	f()
+}
+
+// smoothGeoid is a smooth test surface in meters at the input latitude and longitude in degrees.
+func smoothGeoid(lat, lng float64) float64 {
+	return 30*math.Sin(2*lat*Deg)*math.Cos(lng*Deg) + 20*math.Cos(lat*Deg)*math.Sin(2*lng*Deg)
+}
+
+// globalGrid returns a global grid file of smoothGeoid at spacing d degrees.
+func globalGrid(d float64) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "-90.0 90.0 0.0 360.0 %f %f\n", d, d)
+	for lat:=90.0; lat>= -90; lat -= d {
+		for lng:=0.0; lng<=360; lng += d {
+			fmt.Fprintf(&b, " %.9f", smoothGeoid(lat, lng))
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+func TestInterpolationString(t *testing.T) {
+	for _, m := range interpolations {
+		withInterpolation(t, m, func() {
+			if GetInterpolation()!=m {
+				t.Errorf("expected interpolation %v, got %v", m, GetInterpolation())
+			}
+		})
+	}
+	names := []string{"nearest", "bilinear", "bicubic", "spline"}
+	for i, m := range interpolations {
+		if m.String()!=names[i] {
+			t.Errorf("expected %s, got %s", names[i], m)
+		}
+	}
+	for _, m := range []Interpolation{-1, Spline+1} {
+		if err := SetInterpolation(m); err == nil {
+			t.Errorf("expected an error setting interpolation %v", m)
+		}
+	}
+	if GetInterpolation()!=Bilinear {
+		t.Errorf("expected default interpolation bilinear, got %v", GetInterpolation())
+	}
+}
+
+func TestInterpolationGridPoints(t *testing.T) {
+	withGrid(t, globalGrid(10), func() {
+		for _, m := range interpolations {
+			withInterpolation(t, m, func() {
+				for _, lat := range []float64{90, 60, 0, -30, -90} {
+					for _, lng := range []float64{0, 10, 180, 350, 360} {
+						h, err := NewLocationGeodetic(lat, lng, 0).HeightAboveMSL()
+						if err != nil {
+							t.Fatal(err)
+						}
+						testDiff(fmt.Sprintf("%v height at %v,%v", m, lat, lng), -h, smoothGeoid(lat, lng), 1e-6, t)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestInterpolationLinear(t *testing.T) {
+	// testGrid is the plane 1 + lng + 3*(1-lat), which all but Nearest reproduce
+	withGrid(t, []byte(testGrid), func() {
+		for _, m := range interpolations[1:] {
+			withInterpolation(t, m, func() {
+				for _, p := range [][2]float64{{0.3, 1.7}, {-0.9, 0.1}, {1, 0.5}, {0.5, 2}} {
+					h, err := NewLocationGeodetic(p[0], p[1], 0).HeightAboveMSL()
+					if err != nil {
+						t.Fatal(err)
+					}
+					testDiff(fmt.Sprintf("%v height at %v,%v", m, p[0], p[1]), -h, 1+p[1]+3*(1-p[0]), 1e-9, t)
+				}
+			})
+		}
+
+		withInterpolation(t, Nearest, func() {
+			h, err := NewLocationGeodetic(0.3, 1.7, 0).HeightAboveMSL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDiff("nearest height", -h, 6, eps, t)
+		})
+
+		if _, err := NewLocationGeodetic(0, 2.5, 0).HeightAboveMSL(); err == nil {
+			t.Errorf("expected an error outside of a regional grid")
+		}
+	})
+}
+
+func TestInterpolationWrap(t *testing.T) {
+	withGrid(t, globalGrid(10), func() {
+		for _, m := range interpolations[1:] {
+			withInterpolation(t, m, func() {
+				for _, lat := range []float64{47.3, -12.8} {
+					var hs []float64
+					for _, lng := range []float64{-0.0001, 0, 0.0001, 359.9999, 360, 720.0001} {
+						h, err := NewLocationGeodetic(lat, lng, 0).HeightAboveMSL()
+						if err != nil {
+							t.Fatal(err)
+						}
+						hs = append(hs, h)
+					}
+					for _, h := range hs {
+						testDiff(fmt.Sprintf("%v height near the prime meridian at %v", m, lat), h, hs[1], 1e-3, t)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestInterpolationAccuracy(t *testing.T) {
+	withGrid(t, globalGrid(5), func() {
+		maxErr := make(map[Interpolation]float64)
+		for _, m := range interpolations {
+			withInterpolation(t, m, func() {
+				for lat:= -88.3; lat<89; lat += 3.7 {
+					for lng:=0.6; lng<360; lng += 7.3 {
+						h, err := NewLocationGeodetic(lat, lng, 0).HeightAboveMSL()
+						if err != nil {
+							t.Fatal(err)
+						}
+						maxErr[m] = math.Max(maxErr[m], math.Abs(-h-smoothGeoid(lat, lng)))
+					}
+				}
+			})
+			t.Logf("%v maximum error %8.4f m", m, maxErr[m])
+		}
+		for i:=1; i<len(interpolations); i++ {
+			if maxErr[interpolations[i]] >= maxErr[interpolations[i-1]] {
+				t.Errorf("%v maximum error %v is not less than %v maximum error %v", interpolations[i],
+					maxErr[interpolations[i]], interpolations[i-1], maxErr[interpolations[i-1]])
+			}
+		}
+	})
+}
+
+func TestInterpolationMSLRoundTrip(t *testing.T) {
+	withGrid(t, globalGrid(10), func() {
+		for _, m := range interpolations {
+			withInterpolation(t, m, func() {
+				l, err := NewLocationMSL(33.3, 243.3, 1500)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h, err := l.HeightAboveMSL()
+				if err != nil {
+					t.Fatal(err)
+				}
+				testDiff(fmt.Sprintf("%v height above MSL", m), h, 1500, eps, t)
+			})
+		}
+	})
+}
+
+// NGA's reference values from the outintpt.dat file distributed with its interp
+// program, which interpolates the grid by bicubic splines through a 4x4 window.
+func TestInterpolationAgainstNGA(t *testing.T) {
+	if _, err := getGrid(); err != nil {
+		t.Fatal(err)
+	}
+	lats := []float64{38.628155, -14.621217, 46.874319, -23.617446, 38.625473, -0.466744}
+	lngs := []float64{269.779155, 305.021114, 102.448729, 133.874712, 359.999500, 0.002300}
+	hts  := []float64{-31.628, -2.969, -43.575, 15.871, 50.066, 17.329}
+
+	withInterpolation(t, Bicubic, func() {
+		for i:=0; i<len(lats); i++ {
+			h, err := NewLocationGeodetic(lats[i], lngs[i], 0).HeightAboveMSL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDiff("bicubic height", -h, hts[i], 0.001, t)
+		}
+	})
+}
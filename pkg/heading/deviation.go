@@ -0,0 +1,60 @@
+package heading
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// Deviation returns the deviation of a compass in degrees, positive
+// eastwards, at the input compass heading in degrees.
+type Deviation func(compass float64) (deviation float64)
+
+// NewDeviationTable returns the Deviation given by a compass deviation card,
+// which lists the deviation at several compass headings, e.g. every 45º.
+// The deviation is interpolated linearly between the listed headings, going
+// around from the last back to the first.
+func NewDeviationTable(compass, deviation []float64) (d Deviation, err error) {
+	if len(compass)==0 || len(compass)!=len(deviation) {
+		return nil, fmt.Errorf("bad deviation table of %d headings and %d deviations",
+			len(compass), len(deviation))
+	}
+	type entry struct{ h, d float64 }
+	table := make([]entry, len(compass))
+	for i := range compass {
+		table[i] = entry{Normalize(compass[i]), deviation[i]}
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].h<table[j].h })
+	for i:=1; i<len(table); i++ {
+		if table[i].h==table[i-1].h {
+			return nil, fmt.Errorf("bad deviation table with heading %v listed twice", table[i].h)
+		}
+	}
+
+	return func(h float64) float64 {
+		h = Normalize(h)
+		n := len(table)
+		j := sort.Search(n, func(i int) bool { return table[i].h>h })
+		lo, hi := table[(j+n-1)%n], table[j%n]
+		span := Normalize(hi.h - lo.h)
+		if span==0 {
+			return lo.d
+		}
+		f := Normalize(h-lo.h)/span
+		return lo.d + f*(hi.d-lo.d)
+	}, nil
+}
+
+// NewDeviationCoefficients returns the Deviation given by the five classical
+// compass adjustment coefficients A to E, in degrees, as
+//  deviation = A + B sin(h) + C cos(h) + D sin(2h) + E cos(2h)
+// at the compass heading h.
+func NewDeviationCoefficients(a, b, c, d, e float64) (dev Deviation) {
+	return func(h float64) float64 {
+		s, co := math.Sincos(h*egm96.Deg)
+		s2, c2 := math.Sincos(2*h*egm96.Deg)
+		return a + b*s + c*co + d*s2 + e*c2
+	}
+}
@@ -0,0 +1,172 @@
+// Package heading converts between true, magnetic, grid and compass headings
+// using the declination and grid variation of a wmm.MagneticField.
+//
+// All headings are in degrees clockwise from their reference direction:
+// true north, magnetic north, grid north, or the north indicated by a
+// compass, and all results are normalized to the range [0, 360).
+//
+// The declination D is the angle from true north to magnetic north, positive
+// eastwards, so that
+//  true = magnetic + D
+// The grid variation GV is the angle from grid north to magnetic north, so that
+//  grid = magnetic + GV
+// Grid north is parallel to the Greenwich meridian in the polar regions above
+// 55º latitude, and is true north elsewhere, where GV equals D.
+// The deviation of a compass is the angle from compass north to magnetic
+// north caused by the vehicle's own magnetic field, so that
+//  magnetic = compass + deviation
+package heading
+
+import (
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+// Normalize returns the heading h in degrees in the range [0, 360).
+func Normalize(h float64) (n float64) {
+	n = math.Mod(h, 360)
+	if n<0 {
+		n += 360
+	}
+	if n>=360 {
+		// A tiny negative h rounds up to 360
+		n = 0
+	}
+	return n
+}
+
+// Converter converts headings at the location of a magnetic field.
+type Converter struct {
+	field     wmm.MagneticField
+	deviation Deviation
+}
+
+// NewConverter returns a Converter using the declination and grid variation
+// of the magnetic field at its location, with no compass deviation.
+func NewConverter(mf wmm.MagneticField) (c Converter) {
+	return Converter{field: mf}
+}
+
+// ForLocation returns a Converter using the magnetic field of the default
+// wmm Model at the input location and time.
+//
+// As for wmm.CalculateWMMMagneticField, the error is informational, and the
+// Converter can still be used.
+func ForLocation(loc egm96.Location, t time.Time) (c Converter, err error) {
+	mf, err := wmm.CalculateWMMMagneticField(loc, t)
+	return NewConverter(mf), err
+}
+
+// WithDeviation returns a copy of the Converter which applies the deviation
+// d when converting compass headings.
+func (c Converter) WithDeviation(d Deviation) Converter {
+	c.deviation = d
+	return c
+}
+
+// Declination returns the declination D used by the Converter, in degrees.
+func (c Converter) Declination() (d float64) {
+	return c.field.D()
+}
+
+// GridVariation returns the grid variation GV used by the Converter, in degrees.
+func (c Converter) GridVariation() (gv float64) {
+	return c.field.GV(c.field.Location())
+}
+
+// Uncertainty returns the one standard deviation uncertainty in degrees of a
+// heading converted between magnetic or compass headings and true or grid
+// headings, which is the declination uncertainty wmm.MagneticField.ErrD.
+//
+// Conversions between true and grid headings, and between magnetic and
+// compass headings, do not depend on the magnetic model and are exact.
+// Near the magnetic poles, where the horizontal field is weak, the
+// uncertainty becomes very large.
+// It is NaN if the model of the field has no published error model.
+func (c Converter) Uncertainty() (u float64) {
+	return c.field.ErrD()
+}
+
+// MagneticToTrue converts a magnetic heading to a true heading.
+func (c Converter) MagneticToTrue(magnetic float64) (trueHeading float64) {
+	return Normalize(magnetic + c.Declination())
+}
+
+// TrueToMagnetic converts a true heading to a magnetic heading.
+func (c Converter) TrueToMagnetic(trueHeading float64) (magnetic float64) {
+	return Normalize(trueHeading - c.Declination())
+}
+
+// MagneticToGrid converts a magnetic heading to a grid heading.
+func (c Converter) MagneticToGrid(magnetic float64) (grid float64) {
+	return Normalize(magnetic + c.GridVariation())
+}
+
+// GridToMagnetic converts a grid heading to a magnetic heading.
+func (c Converter) GridToMagnetic(grid float64) (magnetic float64) {
+	return Normalize(grid - c.GridVariation())
+}
+
+// TrueToGrid converts a true heading to a grid heading.
+func (c Converter) TrueToGrid(trueHeading float64) (grid float64) {
+	return Normalize(trueHeading - c.Declination() + c.GridVariation())
+}
+
+// GridToTrue converts a grid heading to a true heading.
+func (c Converter) GridToTrue(grid float64) (trueHeading float64) {
+	return Normalize(grid - c.GridVariation() + c.Declination())
+}
+
+// CompassToMagnetic converts a compass heading to a magnetic heading by
+// adding the deviation of the compass, if any.
+func (c Converter) CompassToMagnetic(compass float64) (magnetic float64) {
+	if c.deviation==nil {
+		return Normalize(compass)
+	}
+	return Normalize(compass + c.deviation(Normalize(compass)))
+}
+
+// MagneticToCompass converts a magnetic heading to the heading shown by the
+// compass, the inverse of CompassToMagnetic.
+//
+// Since the deviation is a function of the compass heading, it is found by
+// iteration, which converges for any deviation which changes by less than
+// one degree per degree of heading.
+func (c Converter) MagneticToCompass(magnetic float64) (compass float64) {
+	compass = Normalize(magnetic)
+	if c.deviation==nil {
+		return compass
+	}
+	for i:=0; i<50; i++ {
+		next := Normalize(magnetic - c.deviation(compass))
+		d := math.Remainder(next-compass, 360)
+		compass = next
+		if math.Abs(d)<1e-9 {
+			break
+		}
+	}
+	return compass
+}
+
+// CompassToTrue converts a compass heading to a true heading.
+func (c Converter) CompassToTrue(compass float64) (trueHeading float64) {
+	return c.MagneticToTrue(c.CompassToMagnetic(compass))
+}
+
+// TrueToCompass converts a true heading to a compass heading.
+func (c Converter) TrueToCompass(trueHeading float64) (compass float64) {
+	return c.MagneticToCompass(c.TrueToMagnetic(trueHeading))
+}
+
+// CompassToGrid converts a compass heading to a grid heading.
+func (c Converter) CompassToGrid(compass float64) (grid float64) {
+	return c.MagneticToGrid(c.CompassToMagnetic(compass))
+}
+
+// GridToCompass converts a grid heading to a compass heading.
+func (c Converter) GridToCompass(grid float64) (compass float64) {
+	return c.MagneticToCompass(c.GridToMagnetic(grid))
+}
@@ -0,0 +1,118 @@
+package heading
+
+import (
+	"math"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+func testDiff(name string, actual, expected float64, eps float64, t *testing.T) {
+	if actual - expected > -eps && actual - expected < eps {
+		t.Logf("%s correct: expected %8.4f, got %8.4f", name, expected, actual)
+		return
+	}
+	t.Errorf("%s incorrect: expected %8.4f, got %8.4f", name, expected, actual)
+}
+
+// testAngle compares headings modulo 360º.
+func testAngle(name string, actual, expected float64, eps float64, t *testing.T) {
+	testDiff(name, expected+math.Remainder(actual-expected, 360), expected, eps, t)
+}
+
+func converterAt(lat, lng float64, t *testing.T) (c Converter) {
+	w, err := wmm.LoadModel("../wmm/testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, err := w.MagneticField(egm96.NewLocationGeodetic(lat, lng, 0), wmm.DecimalYear(2022.5).ToTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewConverter(mf)
+}
+
+func TestNormalize(t *testing.T) {
+	hs := []float64{0, 359.5, 360, 725, -10, -730, -1e-15}
+	ns := []float64{0, 359.5, 0, 5, 350, 350, 0}
+	for i, h := range hs {
+		n := Normalize(h)
+		if n<0 || n>=360 {
+			t.Errorf("Normalize(%v)=%v out of range", h, n)
+		}
+		testDiff("Normalize", n, ns[i], 1e-9, t)
+	}
+}
+
+func TestTrueMagnetic(t *testing.T) {
+	// Seattle has an easterly declination of about 15º, so magnetic north is
+	// at about 15º true, and true north at about 345º magnetic.
+	c := converterAt(47.6, -122.3, t)
+	d := c.Declination()
+	testDiff("Seattle declination", d, 15.2, 0.5, t)
+	testAngle("magnetic north in true", c.MagneticToTrue(0), d, 1e-9, t)
+	testAngle("true north in magnetic", c.TrueToMagnetic(0), 360-d, 1e-9, t)
+	testAngle("grid equals true", c.TrueToGrid(123), 123, 1e-9, t)
+	testDiff("uncertainty", c.Uncertainty(), c.field.ErrD(), 1e-12, t)
+
+	for h:=0.0; h<360; h += 7.5 {
+		testAngle("magnetic round trip", c.TrueToMagnetic(c.MagneticToTrue(h)), h, 1e-9, t)
+		testAngle("grid round trip", c.GridToMagnetic(c.MagneticToGrid(h)), h, 1e-9, t)
+		if m := c.MagneticToTrue(h); m<0 || m>=360 {
+			t.Errorf("true heading %v not normalized", m)
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	for _, lng := range []float64{-100, 30} {
+		c := converterAt(80, lng, t)
+		testAngle("north grid variation", c.GridVariation(), c.Declination()-lng, 1e-9, t)
+		testAngle("north true to grid", c.TrueToGrid(90), 90-lng, 1e-9, t)
+		testAngle("north grid to true", c.GridToTrue(90-lng), 90, 1e-9, t)
+		testAngle("north magnetic to grid", c.MagneticToGrid(0), c.GridVariation(), 1e-9, t)
+
+		c = converterAt(-80, lng, t)
+		testAngle("south true to grid", c.TrueToGrid(90), 90+lng, 1e-9, t)
+		testAngle("south grid to magnetic", c.GridToMagnetic(c.TrueToGrid(90)), c.TrueToMagnetic(90), 1e-9, t)
+	}
+}
+
+func TestCompass(t *testing.T) {
+	c := converterAt(47.6, -122.3, t)
+	testAngle("no deviation", c.CompassToMagnetic(-20), 340, 1e-9, t)
+
+	dev, err := NewDeviationTable(
+		[]float64{0, 45, 90, 135, 180, 225, 270, 315},
+		[]float64{2, 3, 1, -1, -2, -3, -1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("table deviation", dev(22.5), 2.5, 1e-9, t)
+	testDiff("table deviation wrap", dev(337.5), 1.5, 1e-9, t)
+	testDiff("table deviation negative", dev(-22.5), 1.5, 1e-9, t)
+
+	c = c.WithDeviation(dev)
+	testAngle("compass to magnetic", c.CompassToMagnetic(90), 91, 1e-9, t)
+	testAngle("compass to true", c.CompassToTrue(0), 2+c.Declination(), 1e-9, t)
+	for h:=0.0; h<360; h += 5 {
+		testAngle("compass round trip", c.CompassToTrue(c.TrueToCompass(h)), h, 1e-6, t)
+		testAngle("compass grid round trip", c.CompassToGrid(c.GridToCompass(h)), h, 1e-6, t)
+	}
+
+	dev = NewDeviationCoefficients(0.5, 2, -1, 0.5, 0.25)
+	testDiff("coefficient deviation at 0", dev(0), 0.5-1+0.25, 1e-9, t)
+	testDiff("coefficient deviation at 90", dev(90), 0.5+2-0.25, 1e-9, t)
+	c = c.WithDeviation(dev)
+	for h:=0.0; h<360; h += 5 {
+		testAngle("coefficient round trip", c.CompassToMagnetic(c.MagneticToCompass(h)), h, 1e-6, t)
+	}
+
+	if _, err = NewDeviationTable([]float64{0, 360}, []float64{1, 2}); err == nil {
+		t.Errorf("expected an error for a repeated heading")
+	}
+	if _, err = NewDeviationTable([]float64{0}, nil); err == nil {
+		t.Errorf("expected an error for mismatched lengths")
+	}
+}
@@ -0,0 +1,190 @@
+// Package magnetometer relates the readings of a vehicle's magnetometer to
+// the modelled geomagnetic field: the reading expected at a given attitude,
+// and the attitude error implied by an actual reading.
+//
+// The navigation frame is North-East-Down (NED), in which the field is given
+// by wmm.MagneticField.Ellipsoidal, and the body frame is Forward-Right-Down
+// (FRD), which coincides with NED when the vehicle is level and pointing
+// north.  Attitudes follow the aerospace convention: a rotation by yaw about
+// Down, then pitch about the new Right axis, then roll about the new Forward
+// axis takes the NED axes to the body axes.
+package magnetometer
+
+import (
+	"math"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+// Vector is a three dimensional vector, such as a magnetic field in nT,
+// in either the NED or the FRD frame.
+type Vector [3]float64
+
+// Norm returns the length of the vector.
+func (v Vector) Norm() float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
+
+func (v Vector) dot(u Vector) float64 {
+	return v[0]*u[0] + v[1]*u[1] + v[2]*u[2]
+}
+
+func (v Vector) cross(u Vector) Vector {
+	return Vector{v[1]*u[2] - v[2]*u[1], v[2]*u[0] - v[0]*u[2], v[0]*u[1] - v[1]*u[0]}
+}
+
+// Attitude is the orientation of a vehicle relative to the NED frame.
+type Attitude interface {
+	// BodyToNED returns the direction cosine matrix which rotates vectors in
+	// the FRD body frame to the NED frame.
+	BodyToNED() [3][3]float64
+}
+
+// Euler is an attitude given by Euler angles in degrees.
+type Euler struct {
+	Roll, Pitch, Yaw float64
+}
+
+// BodyToNED returns the direction cosine matrix which rotates vectors in
+// the FRD body frame to the NED frame.
+func (e Euler) BodyToNED() (c [3][3]float64) {
+	sr, cr := math.Sincos(e.Roll*egm96.Deg)
+	sp, cp := math.Sincos(e.Pitch*egm96.Deg)
+	sy, cy := math.Sincos(e.Yaw*egm96.Deg)
+	return [3][3]float64{
+		{cp*cy, sr*sp*cy - cr*sy, cr*sp*cy + sr*sy},
+		{cp*sy, sr*sp*sy + cr*cy, cr*sp*sy - sr*cy},
+		{-sp, sr*cp, cr*cp},
+	}
+}
+
+// Quaternion returns the attitude as a unit quaternion.
+func (e Euler) Quaternion() (q Quaternion) {
+	sr, cr := math.Sincos(e.Roll*egm96.Deg/2)
+	sp, cp := math.Sincos(e.Pitch*egm96.Deg/2)
+	sy, cy := math.Sincos(e.Yaw*egm96.Deg/2)
+	return Quaternion{
+		W: cr*cp*cy + sr*sp*sy,
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+	}
+}
+
+// Quaternion is an attitude given by a quaternion which rotates vectors in
+// the FRD body frame to the NED frame.  It need not be normalized.
+type Quaternion struct {
+	W, X, Y, Z float64
+}
+
+// normalized returns the quaternion scaled to unit length.
+func (q Quaternion) normalized() Quaternion {
+	n := math.Sqrt(q.W*q.W + q.X*q.X + q.Y*q.Y + q.Z*q.Z)
+	return Quaternion{q.W/n, q.X/n, q.Y/n, q.Z/n}
+}
+
+// BodyToNED returns the direction cosine matrix which rotates vectors in
+// the FRD body frame to the NED frame.
+func (q Quaternion) BodyToNED() (c [3][3]float64) {
+	q = q.normalized()
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	return [3][3]float64{
+		{1 - 2*(y*y+z*z), 2*(x*y-w*z), 2*(x*z+w*y)},
+		{2*(x*y+w*z), 1 - 2*(x*x+z*z), 2*(y*z-w*x)},
+		{2*(x*z-w*y), 2*(y*z+w*x), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// Euler returns the attitude as Euler angles in degrees.
+// At a pitch of ±90º, the roll and yaw are not unique and all of the
+// rotation about Down is given as yaw.
+func (q Quaternion) Euler() (e Euler) {
+	q = q.normalized()
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	sp := math.Max(-1, math.Min(1, 2*(w*y-z*x)))
+	e.Pitch = math.Asin(sp)/egm96.Deg
+	if math.Abs(sp)>1-1e-12 {
+		e.Yaw = -2*math.Copysign(1, sp)*math.Atan2(x, w)/egm96.Deg
+		return e
+	}
+	e.Roll = math.Atan2(2*(w*x+y*z), 1-2*(x*x+y*y))/egm96.Deg
+	e.Yaw = math.Atan2(2*(w*z+x*y), 1-2*(y*y+z*z))/egm96.Deg
+	return e
+}
+
+// toNED rotates the body frame vector v to the NED frame.
+func toNED(att Attitude, v Vector) (u Vector) {
+	c := att.BodyToNED()
+	for i := range u {
+		for j := range v {
+			u[i] += c[i][j]*v[j]
+		}
+	}
+	return u
+}
+
+// toBody rotates the NED frame vector v to the body frame.
+func toBody(att Attitude, v Vector) (u Vector) {
+	c := att.BodyToNED()
+	for i := range u {
+		for j := range v {
+			u[i] += c[j][i]*v[j]
+		}
+	}
+	return u
+}
+
+// fieldNED returns the modelled field in the NED frame, in nT.
+func fieldNED(mf wmm.MagneticField) (v Vector) {
+	x, y, z, _, _, _ := mf.Ellipsoidal()
+	return Vector{x, y, z}
+}
+
+// Expected returns the magnetic field, in nT, which an ideal magnetometer
+// should measure at the attitude att, both in the NED frame and in the FRD
+// body frame of the vehicle.
+func Expected(mf wmm.MagneticField, att Attitude) (ned, frd Vector) {
+	ned = fieldNED(mf)
+	return ned, toBody(att, ned)
+}
+
+// Residual describes the difference between a measured and an expected
+// magnetometer reading.
+type Residual struct {
+	Body      Vector  // Measured minus expected field in the FRD frame, nT
+	Magnitude float64 // Measured minus expected field strength, nT
+	Angle     float64 // Angle between the measured and expected field directions, º
+	Rotation  Vector  // Rotation vector in the FRD frame which turns the expected direction onto the measured one, º
+	Yaw       float64 // Correction to add to the yaw of the attitude to align the horizontal field, º
+	Dip       float64 // Inclination of the measured minus the expected field in the NED frame, º
+}
+
+// AttitudeResidual returns the residual between the field measured by a
+// magnetometer in the FRD body frame, in nT, and the field expected from
+// the model at the attitude att.
+//
+// The Yaw residual is the heading error of att implied by the measurement,
+// which is unobservable only when the field is vertical.
+// The magnetometer can only determine two of the three degrees of freedom of
+// the attitude; rotations about the field direction leave the reading unchanged.
+func AttitudeResidual(mf wmm.MagneticField, att Attitude, measured Vector) (r Residual) {
+	ned, frd := Expected(mf, att)
+	for i := range r.Body {
+		r.Body[i] = measured[i] - frd[i]
+	}
+	r.Magnitude = measured.Norm() - frd.Norm()
+
+	axis := frd.cross(measured)
+	r.Angle = math.Atan2(axis.Norm(), frd.dot(measured))/egm96.Deg
+	if n := axis.Norm(); n>0 {
+		for i := range axis {
+			r.Rotation[i] = axis[i]/n*r.Angle
+		}
+	}
+
+	m := toNED(att, measured)
+	r.Yaw = math.Remainder(math.Atan2(ned[1], ned[0])-math.Atan2(m[1], m[0]), 2*math.Pi)/egm96.Deg
+	r.Dip = (math.Atan2(m[2], math.Hypot(m[0], m[1])) - math.Atan2(ned[2], math.Hypot(ned[0], ned[1])))/egm96.Deg
+	return r
+}
@@ -0,0 +1,111 @@
+package magnetometer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+func testDiff(name string, actual, expected float64, eps float64, t *testing.T) {
+	if actual - expected > -eps && actual - expected < eps {
+		t.Logf("%s correct: expected %8.4f, got %8.4f", name, expected, actual)
+		return
+	}
+	t.Errorf("%s incorrect: expected %8.4f, got %8.4f", name, expected, actual)
+}
+
+func testField(t *testing.T) (mf wmm.MagneticField) {
+	w, err := wmm.LoadModel("../wmm/testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, err = w.MagneticField(egm96.NewLocationGeodetic(47.6, -122.3, 100), wmm.DecimalYear(2022.5).ToTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mf
+}
+
+func TestExpectedLevel(t *testing.T) {
+	mf := testField(t)
+	x, y, z, _, _, _ := mf.Ellipsoidal()
+
+	ned, frd := Expected(mf, Euler{})
+	for i, v := range []float64{x, y, z} {
+		testDiff("NED", ned[i], v, 1e-9, t)
+		testDiff("FRD level north", frd[i], v, 1e-9, t)
+	}
+
+	// Pointing at magnetic north, the field is all forward and down
+	_, frd = Expected(mf, Euler{Yaw: mf.D()})
+	testDiff("forward", frd[0], mf.H(), 1e-6, t)
+	testDiff("right", frd[1], 0, 1e-6, t)
+	testDiff("down", frd[2], z, 1e-6, t)
+
+	// Pointing east, the north component is to the left
+	_, frd = Expected(mf, Euler{Yaw: 90})
+	testDiff("east forward", frd[0], y, 1e-6, t)
+	testDiff("east right", frd[1], -x, 1e-6, t)
+
+	// Rolled right 90º, the right wing points down and the east component is up
+	_, frd = Expected(mf, Euler{Roll: 90})
+	testDiff("rolled right", frd[1], z, 1e-6, t)
+	testDiff("rolled down", frd[2], -y, 1e-6, t)
+
+	// Pitched up 90º, the down component is backwards
+	_, frd = Expected(mf, Euler{Pitch: 90})
+	testDiff("pitched forward", frd[0], -z, 1e-6, t)
+	testDiff("pitched down", frd[2], x, 1e-6, t)
+}
+
+func TestQuaternion(t *testing.T) {
+	mf := testField(t)
+	for _, e := range []Euler{{0, 0, 0}, {10, 20, 30}, {-45, 60, -170}, {170, -80, 95}, {0, 90, 40}, {0, -90, -40}} {
+		q := e.Quaternion()
+		_, fe := Expected(mf, e)
+		_, fq := Expected(mf, Quaternion{2*q.W, 2*q.X, 2*q.Y, 2*q.Z})
+		for i := range fe {
+			testDiff("quaternion field", fq[i], fe[i], 1e-6, t)
+		}
+		e2 := q.Euler()
+		testDiff("roll", e2.Roll, e.Roll, 1e-6, t)
+		testDiff("pitch", e2.Pitch, e.Pitch, 1e-6, t)
+		testDiff("yaw", e2.Yaw, e.Yaw, 1e-6, t)
+	}
+}
+
+func TestAttitudeResidual(t *testing.T) {
+	mf := testField(t)
+	est := Euler{Roll: 5, Pitch: -3, Yaw: 42}
+
+	_, frd := Expected(mf, est)
+	r := AttitudeResidual(mf, est, frd)
+	testDiff("no residual angle", r.Angle, 0, 1e-6, t)
+	testDiff("no residual yaw", r.Yaw, 0, 1e-6, t)
+	testDiff("no residual magnitude", r.Magnitude, 0, 1e-6, t)
+
+	// A reading taken at a yaw 3º greater than estimated
+	_, frd = Expected(mf, Euler{Roll: 5, Pitch: -3, Yaw: 45})
+	r = AttitudeResidual(mf, est, frd)
+	testDiff("yaw residual", r.Yaw, 3, 1e-6, t)
+	testDiff("magnitude residual", r.Magnitude, 0, 1e-6, t)
+	testDiff("dip residual", r.Dip, 0, 1e-6, t)
+	expected := 3*mf.H()/mf.F()
+	testDiff("angle residual", r.Angle, expected, 0.01, t)
+	testDiff("rotation", r.Rotation.Norm(), r.Angle, 1e-9, t)
+
+	// A reading 1% stronger
+	_, frd = Expected(mf, est)
+	for i := range frd {
+		frd[i] *= 1.01
+	}
+	r = AttitudeResidual(mf, est, frd)
+	testDiff("scaled magnitude", r.Magnitude, 0.01*mf.F(), 1e-6, t)
+	testDiff("scaled angle", r.Angle, 0, 1e-6, t)
+	testDiff("scaled body", r.Body.Norm(), 0.01*mf.F(), 1e-6, t)
+	if math.IsNaN(r.Rotation[0]) {
+		t.Errorf("rotation should be zero, got %v", r.Rotation)
+	}
+}
@@ -0,0 +1,288 @@
+package magnetometer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+// minCalibrationSamples is the fewest samples from which the nine parameters
+// of the ellipsoid can be fitted.
+const minCalibrationSamples = 9
+
+// Calibration corrects raw magnetometer samples for hard-iron and soft-iron
+// distortion.
+//
+// Hard-iron distortion, from magnetized material fixed to the vehicle, adds a
+// constant offset to the samples.  Soft-iron distortion, from magnetically
+// soft material and from the scale factors and cross-coupling of the sensor
+// axes, turns the sphere of undistorted samples into an ellipsoid.
+type Calibration struct {
+	Offset   Vector        // Hard-iron offset, in the units of the raw samples
+	SoftIron [3][3]float64 // Soft-iron correction from offset raw samples to nT
+}
+
+// Apply returns the calibrated sample, in nT, of the raw sample:
+//  SoftIron × (raw - Offset)
+func (c Calibration) Apply(raw Vector) (v Vector) {
+	for i := range v {
+		for j := range raw {
+			v[i] += c.SoftIron[i][j]*(raw[j]-c.Offset[j])
+		}
+	}
+	return v
+}
+
+// CalibrationStats describes how well the calibrated samples match the
+// modelled field.
+type CalibrationStats struct {
+	N         int     // Number of samples
+	Field     float64 // Modelled total intensity F to which the samples are scaled, nT
+	MeanError float64 // Mean of the calibrated intensity minus F, nT
+	RMSError  float64 // Root mean square of the calibrated intensity minus F, nT
+	MaxError  float64 // Largest absolute calibrated intensity minus F, nT
+
+	// The inclination statistics are only calculated when accelerometer
+	// samples are given, and are otherwise NaN.
+	Inclination      float64 // Mean dip angle of the calibrated samples, º
+	InclinationError float64 // Mean dip angle minus I, º
+	InclinationRMS   float64 // Root mean square of the dip angle minus I, º
+}
+
+// Calibrate fits an ellipsoid to raw magnetometer samples taken in many
+// orientations at a single location, where the modelled field is mf, and
+// returns the Calibration which maps them onto a sphere of radius mf.F().
+//
+// The raw samples may be in any units.  They should cover as much of the
+// ellipsoid as possible, e.g. by rotating the vehicle through all headings
+// while pitched and rolled; samples from a single plane are not enough.
+//
+// The soft-iron correction is taken to be symmetric, which leaves the
+// calibrated axes as close as possible to the sensor axes.
+//
+// If accel is not nil, it must hold the accelerometer sample, in the same
+// body frame, taken with each magnetometer sample while the vehicle was not
+// accelerating.  The dip angles of the calibrated samples are then compared
+// with the modelled inclination mf.I(), which checks the alignment of the
+// magnetometer with the accelerometer and any remaining distortion.
+//
+// An error is returned if there are too few samples, or if they do not
+// determine an ellipsoid.
+func Calibrate(mf wmm.MagneticField, raw, accel []Vector) (cal Calibration, stats CalibrationStats, err error) {
+	n := len(raw)
+	if n<minCalibrationSamples {
+		return cal, stats, fmt.Errorf("need at least %d magnetometer samples to calibrate, got %d",
+			minCalibrationSamples, n)
+	}
+	if accel != nil && len(accel)!=n {
+		return cal, stats, fmt.Errorf("got %d accelerometer samples for %d magnetometer samples", len(accel), n)
+	}
+
+	// Center and scale the samples for a well-conditioned fit
+	var c0 Vector
+	for _, v := range raw {
+		for i := range c0 {
+			c0[i] += v[i]/float64(n)
+		}
+	}
+	var s float64
+	for _, v := range raw {
+		for i := range v {
+			s += (v[i]-c0[i])*(v[i]-c0[i])/float64(n)
+		}
+	}
+	s = math.Sqrt(s)
+	if s==0 {
+		return cal, stats, fmt.Errorf("magnetometer samples are all identical")
+	}
+
+	// Least squares fit of the quadric
+	//  a x² + b y² + c z² + 2h xy + 2g xz + 2f yz + 2p x + 2q y + 2r z = 1
+	var (
+		ata [9][9]float64
+		atb [9]float64
+	)
+	for _, v := range raw {
+		x, y, z := (v[0]-c0[0])/s, (v[1]-c0[1])/s, (v[2]-c0[2])/s
+		row := [9]float64{x*x, y*y, z*z, 2*x*y, 2*x*z, 2*y*z, 2*x, 2*y, 2*z}
+		for i := range row {
+			for j := range row {
+				ata[i][j] += row[i]*row[j]
+			}
+			atb[i] += row[i]
+		}
+	}
+	p, ok := solve9(ata, atb)
+	if !ok {
+		return cal, stats, fmt.Errorf("magnetometer samples do not determine an ellipsoid")
+	}
+
+	a := [3][3]float64{{p[0], p[3], p[4]}, {p[3], p[1], p[5]}, {p[4], p[5], p[2]}}
+	aInv, ok := invert3(a)
+	if !ok {
+		return cal, stats, fmt.Errorf("magnetometer samples do not determine an ellipsoid")
+	}
+	var o Vector
+	for i := range o {
+		for j := range o {
+			o[i] -= aInv[i][j]*p[6+j]
+		}
+	}
+	k := 1.0
+	for i := range o {
+		for j := range o {
+			k += o[i]*a[i][j]*o[j]
+		}
+	}
+
+	// The offset samples satisfy vᵀ (A/k) v = 1, so the soft-iron correction
+	// is F times the symmetric square root of A/k, which must be positive definite.
+	vals, vecs := eigenSym3(a)
+	for i := range vals {
+		vals[i] /= k
+		if vals[i]<=0 {
+			return cal, stats, fmt.Errorf("magnetometer samples do not fit an ellipsoid")
+		}
+	}
+	f := mf.F()
+	for i := range o {
+		cal.Offset[i] = c0[i] + s*o[i]
+		for j := range o {
+			for l := range vals {
+				cal.SoftIron[i][j] += vecs[i][l]*math.Sqrt(vals[l])*vecs[j][l]*f/s
+			}
+		}
+	}
+
+	stats = calibrationStats(mf, cal, raw, accel)
+	return cal, stats, nil
+}
+
+// calibrationStats compares the calibrated samples with the modelled field.
+func calibrationStats(mf wmm.MagneticField, cal Calibration, raw, accel []Vector) (stats CalibrationStats) {
+	stats.N = len(raw)
+	stats.Field = mf.F()
+	for _, v := range raw {
+		e := cal.Apply(v).Norm() - stats.Field
+		stats.MeanError += e
+		stats.RMSError += e*e
+		stats.MaxError = math.Max(stats.MaxError, math.Abs(e))
+	}
+	stats.MeanError /= float64(stats.N)
+	stats.RMSError = math.Sqrt(stats.RMSError/float64(stats.N))
+
+	stats.Inclination, stats.InclinationError, stats.InclinationRMS = math.NaN(), math.NaN(), math.NaN()
+	if accel==nil {
+		return stats
+	}
+	var sum, sum2 float64
+	for i, v := range raw {
+		m := cal.Apply(v)
+		// The specific force at rest points up
+		up := accel[i]
+		dip := math.Asin(-m.dot(up)/(m.Norm()*up.Norm()))/egm96.Deg
+		sum += dip
+		sum2 += (dip-mf.I())*(dip-mf.I())
+	}
+	stats.Inclination = sum/float64(stats.N)
+	stats.InclinationError = stats.Inclination - mf.I()
+	stats.InclinationRMS = math.Sqrt(sum2/float64(stats.N))
+	return stats
+}
+
+// solve9 solves the 9×9 linear system m x = b by Gaussian elimination with
+// partial pivoting, returning false if m is singular.
+func solve9(m [9][9]float64, b [9]float64) (x [9]float64, ok bool) {
+	const n = 9
+	var scale float64
+	for i := range m {
+		for j := range m[i] {
+			scale = math.Max(scale, math.Abs(m[i][j]))
+		}
+	}
+	for c:=0; c<n; c++ {
+		piv := c
+		for r:=c+1; r<n; r++ {
+			if math.Abs(m[r][c])>math.Abs(m[piv][c]) {
+				piv = r
+			}
+		}
+		if math.Abs(m[piv][c])<=1e-12*scale {
+			return x, false
+		}
+		m[c], m[piv] = m[piv], m[c]
+		b[c], b[piv] = b[piv], b[c]
+		for r:=c+1; r<n; r++ {
+			f := m[r][c]/m[c][c]
+			for k:=c; k<n; k++ {
+				m[r][k] -= f*m[c][k]
+			}
+			b[r] -= f*b[c]
+		}
+	}
+	for r:=n-1; r>=0; r-- {
+		x[r] = b[r]
+		for k:=r+1; k<n; k++ {
+			x[r] -= m[r][k]*x[k]
+		}
+		x[r] /= m[r][r]
+	}
+	return x, true
+}
+
+// invert3 returns the inverse of the 3×3 matrix m, or false if it is singular.
+func invert3(m [3][3]float64) (inv [3][3]float64, ok bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det==0 {
+		return inv, false
+	}
+	for i:=0; i<3; i++ {
+		for j:=0; j<3; j++ {
+			// Cofactor of m[j][i]
+			r0, r1 := (j+1)%3, (j+2)%3
+			c0, c1 := (i+1)%3, (i+2)%3
+			inv[i][j] = (m[r0][c0]*m[r1][c1] - m[r0][c1]*m[r1][c0])/det
+		}
+	}
+	return inv, true
+}
+
+// eigenSym3 returns the eigenvalues and eigenvectors, as the columns of vecs,
+// of the symmetric 3×3 matrix m by the cyclic Jacobi method.
+func eigenSym3(m [3][3]float64) (vals [3]float64, vecs [3][3]float64) {
+	vecs = [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for sweep:=0; sweep<50; sweep++ {
+		off := m[0][1]*m[0][1] + m[0][2]*m[0][2] + m[1][2]*m[1][2]
+		if off<1e-30*(m[0][0]*m[0][0]+m[1][1]*m[1][1]+m[2][2]*m[2][2]) {
+			break
+		}
+		for p:=0; p<2; p++ {
+			for q:=p+1; q<3; q++ {
+				if m[p][q]==0 {
+					continue
+				}
+				theta := (m[q][q]-m[p][p])/(2*m[p][q])
+				t := math.Copysign(1, theta)/(math.Abs(theta)+math.Sqrt(theta*theta+1))
+				c := 1/math.Sqrt(t*t+1)
+				s := t*c
+				for k:=0; k<3; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p], m[k][q] = c*mkp-s*mkq, s*mkp+c*mkq
+				}
+				for k:=0; k<3; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k], m[q][k] = c*mpk-s*mqk, s*mpk+c*mqk
+				}
+				for k:=0; k<3; k++ {
+					vkp, vkq := vecs[k][p], vecs[k][q]
+					vecs[k][p], vecs[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	return [3]float64{m[0][0], m[1][1], m[2][2]}, vecs
+}
@@ -0,0 +1,116 @@
+package magnetometer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// distortedSamples returns the raw samples of a magnetometer with a known
+// hard-iron offset and symmetric soft-iron distortion, in sensor counts of
+// 10nT, over a sweep of attitudes, with the matching calibrated samples and
+// accelerometer samples.
+func distortedSamples(t *testing.T, noise float64) (raw, want, accel []Vector, offset Vector, dist [3][3]float64) {
+	mf := testField(t)
+	offset = Vector{120, -340, 55}
+	dist = [3][3]float64{{0.11, 0.005, 0.002}, {0.005, 0.09, -0.003}, {0.002, -0.003, 0.105}}
+	rnd := rand.New(rand.NewSource(1))
+	for roll:=-60.0; roll<=60; roll += 30 {
+		for pitch:=-60.0; pitch<=60; pitch += 30 {
+			for yaw:=0.0; yaw<360; yaw += 30 {
+				att := Euler{Roll: roll, Pitch: pitch, Yaw: yaw}
+				_, frd := Expected(mf, att)
+				var r Vector
+				for i := range r {
+					r[i] = offset[i] + noise*rnd.NormFloat64()
+					for j := range frd {
+						r[i] += dist[i][j]*frd[j]
+					}
+				}
+				raw = append(raw, r)
+				want = append(want, frd)
+				accel = append(accel, toBody(att, Vector{0, 0, -9.8}))
+			}
+		}
+	}
+	return raw, want, accel, offset, dist
+}
+
+func TestCalibrate(t *testing.T) {
+	mf := testField(t)
+	raw, want, accel, offset, dist := distortedSamples(t, 0)
+
+	cal, stats, err := Calibrate(mf, raw, accel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range offset {
+		testDiff("hard-iron offset", cal.Offset[i], offset[i], 1e-6, t)
+	}
+	inv, _ := invert3(dist)
+	for i := range inv {
+		for j := range inv[i] {
+			testDiff("soft-iron matrix", cal.SoftIron[i][j], inv[i][j], 1e-8, t)
+		}
+	}
+	for k := range raw {
+		v := cal.Apply(raw[k])
+		for i := range v {
+			if math.Abs(v[i]-want[k][i])>1e-3 {
+				t.Errorf("calibrated sample %d is %v, expected %v", k, v, want[k])
+				break
+			}
+		}
+	}
+
+	if stats.N!=len(raw) {
+		t.Errorf("expected %d samples, got %d", len(raw), stats.N)
+	}
+	testDiff("field", stats.Field, mf.F(), 1e-9, t)
+	testDiff("mean error", stats.MeanError, 0, 1e-6, t)
+	testDiff("RMS error", stats.RMSError, 0, 1e-6, t)
+	testDiff("max error", stats.MaxError, 0, 1e-6, t)
+	testDiff("inclination", stats.Inclination, mf.I(), 1e-6, t)
+	testDiff("inclination error", stats.InclinationError, 0, 1e-6, t)
+	testDiff("inclination RMS", stats.InclinationRMS, 0, 1e-6, t)
+}
+
+func TestCalibrateNoise(t *testing.T) {
+	mf := testField(t)
+	// Noise of 2 counts, i.e. about 20nT
+	raw, _, _, offset, _ := distortedSamples(t, 2)
+
+	cal, stats, err := Calibrate(mf, raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range offset {
+		testDiff("noisy hard-iron offset", cal.Offset[i], offset[i], 1, t)
+	}
+	testDiff("noisy RMS error", stats.RMSError, 20, 10, t)
+	if !math.IsNaN(stats.Inclination) {
+		t.Errorf("expected no inclination without accelerometer samples, got %v", stats.Inclination)
+	}
+}
+
+func TestCalibrateErrors(t *testing.T) {
+	mf := testField(t)
+	raw, _, accel, _, _ := distortedSamples(t, 0)
+
+	if _, _, err := Calibrate(mf, raw[:5], nil); err == nil {
+		t.Errorf("expected an error for too few samples")
+	}
+	if _, _, err := Calibrate(mf, raw, accel[:10]); err == nil {
+		t.Errorf("expected an error for mismatched accelerometer samples")
+	}
+
+	// Samples in a single plane, from turning in yaw while level
+	var flat []Vector
+	for yaw:=0.0; yaw<360; yaw += 10 {
+		_, frd := Expected(mf, Euler{Yaw: yaw})
+		flat = append(flat, frd)
+	}
+	if _, _, err := Calibrate(mf, flat, nil); err == nil {
+		t.Errorf("expected an error for planar samples")
+	}
+}
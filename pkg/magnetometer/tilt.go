@@ -0,0 +1,97 @@
+package magnetometer
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/heading"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+// Thresholds are the largest differences between a magnetometer sample and
+// the modelled field which are accepted as free of local magnetic interference.
+type Thresholds struct {
+	Intensity float64 // Largest difference in total intensity from F, nT
+	Dip       float64 // Largest difference in dip angle from I, º
+}
+
+// DefaultThresholds are suitable for a calibrated magnetometer away from
+// steel structures, about 5% of the total intensity at mid latitudes.
+var DefaultThresholds = Thresholds{Intensity: 2500, Dip: 3}
+
+// Compass is a tilt-compensated compass reading.
+type Compass struct {
+	True        float64 // True heading, º
+	Magnetic    float64 // Magnetic heading, º
+	Uncertainty float64 // Uncertainty of the true heading from the model declination, º, NaN if unknown
+	Roll, Pitch float64 // Attitude from the accelerometer, º
+
+	Intensity      float64 // Total intensity of the sample, nT
+	Dip            float64 // Dip angle of the sample below the horizontal, º
+	IntensityError float64 // Intensity of the sample minus F, nT
+	DipError       float64 // Dip angle of the sample minus I, º
+
+	// Interference is set when the intensity or dip angle of the sample
+	// differs from the model by more than the thresholds, which usually
+	// means that the heading is affected by local magnetic interference.
+	Interference bool
+}
+
+// ErrNoGravity is returned when the accelerometer sample is zero, so that
+// the direction of gravity is unknown.
+var ErrNoGravity = errors.New("accelerometer sample is zero")
+
+// TiltCompensatedHeading returns the tilt-compensated compass reading of the
+// magnetometer sample mag and accelerometer sample accel, both in the FRD
+// body frame, at the input location and time, using the modelled field of
+// the default wmm Model.
+// See TiltCompensate.
+//
+// As for wmm.CalculateWMMMagneticField, an error from the model is
+// informational and the reading is still calculated.
+func TiltCompensatedHeading(mag, accel Vector, loc egm96.Location, t time.Time, th Thresholds) (c Compass, err error) {
+	mf, err := wmm.CalculateWMMMagneticField(loc, t)
+	c, e := TiltCompensate(mf, mag, accel, th)
+	if e != nil {
+		return c, e
+	}
+	return c, err
+}
+
+// TiltCompensate returns the tilt-compensated compass reading of the
+// magnetometer sample mag and accelerometer sample accel, both in the FRD
+// body frame, using the declination and inclination of the modelled field mf.
+//
+// The accelerometer measures the specific force, which for a vehicle at
+// rest or unaccelerated points up, e.g. (0, 0, -9.8) when level.
+// The roll and pitch are found from it, the magnetometer sample is rotated
+// to the horizontal, and its direction gives the magnetic heading.
+// The true heading adds the modelled declination.
+//
+// The units of the magnetometer sample must be nT for the intensity checks,
+// but any units may be used for the accelerometer sample.
+func TiltCompensate(mf wmm.MagneticField, mag, accel Vector, th Thresholds) (c Compass, err error) {
+	if accel.Norm()==0 {
+		return c, ErrNoGravity
+	}
+	roll := math.Atan2(-accel[1], -accel[2])
+	pitch := math.Atan2(accel[0], math.Hypot(accel[1], accel[2]))
+	c.Roll, c.Pitch = roll/egm96.Deg, pitch/egm96.Deg
+
+	// Undo only the roll and pitch, with zero yaw, to level the sample in a frame
+	// turned with the vehicle, in which the field points at minus the heading
+	h := toNED(Euler{Roll: c.Roll, Pitch: c.Pitch}, mag)
+	conv := heading.NewConverter(mf)
+	c.Magnetic = heading.Normalize(math.Atan2(-h[1], h[0])/egm96.Deg)
+	c.True = conv.MagneticToTrue(c.Magnetic)
+	c.Uncertainty = conv.Uncertainty()
+
+	c.Intensity = mag.Norm()
+	c.Dip = math.Atan2(h[2], math.Hypot(h[0], h[1]))/egm96.Deg
+	c.IntensityError = c.Intensity - mf.F()
+	c.DipError = c.Dip - mf.I()
+	c.Interference = math.Abs(c.IntensityError)>th.Intensity || math.Abs(c.DipError)>th.Dip
+	return c, nil
+}
@@ -0,0 +1,85 @@
+package magnetometer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/wmm"
+)
+
+func TestTiltCompensate(t *testing.T) {
+	mf := testField(t)
+	for _, att := range []Euler{{0, 0, 0}, {10, -5, 30}, {-30, 20, 200}, {45, 45, -100}, {170, 10, 80}} {
+		_, mag := Expected(mf, att)
+		accel := toBody(att, Vector{0, 0, -9.8})
+		c, err := TiltCompensate(mf, mag, accel, DefaultThresholds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDiff("roll", c.Roll, att.Roll, 1e-6, t)
+		testDiff("pitch", c.Pitch, att.Pitch, 1e-6, t)
+		testDiff("true heading", math.Remainder(c.True-att.Yaw, 360), 0, 1e-6, t)
+		if c.True<0 || c.True>=360 || c.Magnetic<0 || c.Magnetic>=360 {
+			t.Errorf("headings %v, %v not normalized", c.True, c.Magnetic)
+		}
+		testDiff("magnetic heading", math.Remainder(c.Magnetic-(att.Yaw-mf.D()), 360), 0, 1e-6, t)
+		testDiff("intensity error", c.IntensityError, 0, 1e-6, t)
+		testDiff("dip error", c.DipError, 0, 1e-6, t)
+		testDiff("uncertainty", c.Uncertainty, mf.ErrD(), 1e-12, t)
+		if c.Interference {
+			t.Errorf("unexpected interference at attitude %v", att)
+		}
+	}
+}
+
+func TestTiltInterference(t *testing.T) {
+	mf := testField(t)
+	att := Euler{Roll: 5, Pitch: 5, Yaw: 60}
+	accel := toBody(att, Vector{0, 0, -9.8})
+
+	// A stronger field, as from a nearby magnet
+	_, mag := Expected(mf, att)
+	for i := range mag {
+		mag[i] *= 1.1
+	}
+	c, _ := TiltCompensate(mf, mag, accel, DefaultThresholds)
+	testDiff("strong intensity error", c.IntensityError, 0.1*mf.F(), 1e-6, t)
+	testDiff("strong heading unchanged", c.True, 60, 1e-6, t)
+	if !c.Interference {
+		t.Errorf("expected interference for a field 10%% too strong")
+	}
+
+	// A field 5º too steep, as from steel in the floor
+	ned := fieldNED(mf)
+	tilt := 5*egm96.Deg
+	hz := ned[2]*math.Cos(tilt) + mf.H()*math.Sin(tilt)
+	scale := (mf.H()*math.Cos(tilt) - ned[2]*math.Sin(tilt))/mf.H()
+	mag = toBody(att, Vector{ned[0]*scale, ned[1]*scale, hz})
+	c, _ = TiltCompensate(mf, mag, accel, DefaultThresholds)
+	testDiff("steep dip error", c.DipError, 5, 1e-6, t)
+	if !c.Interference {
+		t.Errorf("expected interference for a dip 5º too steep")
+	}
+	c, _ = TiltCompensate(mf, mag, accel, Thresholds{Intensity: 2500, Dip: 6})
+	if c.Interference {
+		t.Errorf("unexpected interference within a 6º dip threshold")
+	}
+
+	if _, err := TiltCompensate(mf, mag, Vector{}, DefaultThresholds); err != ErrNoGravity {
+		t.Errorf("expected ErrNoGravity, got %v", err)
+	}
+}
+
+func TestTiltCompensatedHeading(t *testing.T) {
+	loc := egm96.NewLocationGeodetic(47.6, -122.3, 100)
+	tt := wmm.DecimalYear(2022.5).ToTime()
+	mf, _ := wmm.CalculateWMMMagneticField(loc, tt)
+	att := Euler{Roll: -8, Pitch: 3, Yaw: 135}
+	_, mag := Expected(mf, att)
+	c, err := TiltCompensatedHeading(mag, toBody(att, Vector{0, 0, -9.8}), loc, tt, DefaultThresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDiff("true heading", c.True, 135, 1e-6, t)
+}
@@ -1,11 +1,17 @@
 package polynomial
 
-import "math"
+import (
+	"math"
+	"sync"
+)
 
 type legendreFunctionIndex struct {
 	n, m int
 }
-var legendreFunctionCache = make(map[legendreFunctionIndex]Polynomial)
+var (
+	legendreFunctionCache   = make(map[legendreFunctionIndex]Polynomial)
+	legendreFunctionCacheMu sync.RWMutex
+)
 
 // LegendrePolynomial returns a Polynomial object corresponding to
 // the Legendre Polynomial of degree n.
@@ -22,11 +28,16 @@ func LegendrePolynomial(n int) (p Polynomial) {
 
 // LegendreFunction evaluates the Associated Legendre Function at the given value.
 // Normalization is that given in WMM2015_Report.pdf equation 6.
+// It is safe to call concurrently from multiple goroutines.
 func LegendreFunction(n, m int, x float64) (v float64) {
+	legendreFunctionCacheMu.RLock()
 	p, ok := legendreFunctionCache[legendreFunctionIndex{n,m}]
+	legendreFunctionCacheMu.RUnlock()
 	if !ok {
 		p = LegendrePolynomial(n).Derivative(m)
+		legendreFunctionCacheMu.Lock()
 		legendreFunctionCache[legendreFunctionIndex{n,m}] = p
+		legendreFunctionCacheMu.Unlock()
 	}
 
 	return math.Pow(1-x*x, float64(m)/2)*p.Evaluate(x)
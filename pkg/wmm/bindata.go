@@ -1,6 +1,9 @@
 // Code generated by go-bindata.
 // sources:
 // WMM.COF
+// WMM2015v1.COF
+// WMM2015v2.COF
+// WMM2020.COF
 // DO NOT EDIT!
 
 package wmm
@@ -86,6 +89,66 @@ func wmmCof() (*asset, error) {
 	return a, nil
 }
 
+var _wmm2015v1Cof = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x95\x57\x4d\xba\xe4\x20\x08\xdc\xcf\x29\xbc\x80\x19\xc1\xff\x43\xbc\xf5\xdc\xff\x26\x63\x1a\x01\x0d\x4e\xe6\xbd\xfe\x7a\x91\x74\x5b\x01\xaa\x0a\x34\xce\x39\x87\x01\xf2\x15\xdc\xf2\xf9\xf3\xf5\xe5\xef\x5f\xf9\x1e\xf0\x37\xe4\xdf\xe3\x97\xf4\x6b\xdc\x38\x37\x16\x7b\xec\x29\xb6\x8b\x97\x04\x79\x00\x84\xab\x3a\xfd\x95\x00\xe3\xeb\x3c\xe4\x00\xd7\x7d\xe5\x52\xed\xe5\x42\x06\xd4\xab\xd3\x95\xc7\x72\xb5\x01\xc0\x4f\x84\x71\x9b\x52\xbe\xa2\x89\xe0\xdb\x55\xf6\x08\x48\x11\x5c\x0c\x80\x9f\x94\x3c\xb6\x01\xe5\x55\x3e\xf2\x53\x3c\xd6\x0b\x08\xf0\x09\x0f\xa5\x16\x5a\xe6\x4b\x42\x25\x01\xaf\x34\x01\x30\xb0\x03\x10\x29\x25\x07\x31\xcf\x1a\xb6\x94\x5c\x94\x1f\x67\x4a\x71\x16\x8d\x31\x23\x45\xf7\x00\x5a\x8d\xd7\xfa\x5d\xbb\x12\x01\x28\x25\x44\xce\x1c\x93\xea\xe2\x03\xa7\xf4\xb9\x24\x00\x3d\x2d\x37\x20\x06\x7d\x1e\x8a\x70\xa5\xa0\x80\x51\xce\x5d\x43\x9a\x35\xb8\x1e\xaa\x44\x0f\xa7\x08\xb3\x86\x34\x69\x75\x6d\xb0\x40\xa2\x62\x8b\xeb\xaa\xa6\xd8\x42\x00\x9c\x86\x09\x5c\x74\x53\xb5\x7c\xd7\xa2\x33\xa7\x44\xcb\x62\xe4\x52\xa1\x05\xf6\xc3\xf8\x7f\x65\x78\xa6\x34\xc3\x57\x8e\x10\xb1\x8b\x0d\x7d\x92\x08\x9e\x22\xe4\x59\xf4\x10\x02\x25\x91\xad\x68\xdc\x8b\xce\x5c\x74\x2c\x81\x45\x4d\x75\x2d\x7a\x51\x3a\x11\x60\x16\xdd\xd9\x36\x30\xec\xcd\x35\x78\x50\x2c\x7c\x58\xca\x5c\x34\x24\xa0\x44\x86\x35\xfa\x46\xbe\x62\x81\x00\x89\x6e\xb3\x24\x02\x45\xf3\x00\xb1\x95\x8b\x5c\x74\x16\x06\xe3\x6c\xcb\x25\xf1\x28\xc2\xdd\xe5\x0c\x40\x61\x6b\xb8\xd2\x0f\x3d\x3d\x58\xca\x3b\x4b\x85\x59\x72\x85\x53\x1a\x13\x43\x3a\xdf\xd2\x5a\x98\x25\x57\x91\xa3\xc7\xa8\x6a\x05\x75\x09\x5e\x48\x80\xc9\xd2\xd0\x77\x02\x72\xd3\xc4\x71\xed\x87\x4a\x00\x4e\xa4\xcf\xcc\x7d\x29\x6a\x0d\x58\x85\x9b\x45\xcf\x3f\x41\x13\xa9\xca\x65\xd0\x4b\xe0\x1a\x28\x49\x5f\xc5\xa3\x05\x95\x1a\x58\x2f\x6f\x1d\xaa\xd0\x3a\x5a\xd4\x9a\xcf\x19\x96\x2a\xd3\xea\x2b\xeb\xeb\x73\x92\xc4\x77\x5a\x2b\x01\x50\x26\x4a\x9b\x85\xf6\x6d\x54\x28\x20\x13\x60\x56\x95\x41\x3c\xaa\xa3\x72\xf1\xd2\x1d\x8c\x00\x6c\x39\x99\x46\x98\xd6\xc7\x2e\x1a\x02\x01\x98\x86\x2e\x0f\x8b\xeb\x63\x1f\xc2\x55\xa6\xf5\x96\xbe\xc9\x9c\xce\xba\xaa\xef\xc2\xd5\xcf\x97\xe8\x67\xcb\x79\x3c\x0a\x47\x80\x26\x3a\xa0\x4e\x94\x4d\x87\xb0\xeb\xd0\xc4\xde\x4e\xc6\x17\x6c\xe4\x2f\x92\x44\x02\xe0\x64\x9f\x5b\x7f\x8c\xbe\x75\xd5\xd2\x40\x13\x10\x65\x77\x42\x63\x43\x77\x02\x68\x9f\x4d\x1b\x42\xd2\xae\xd9\xb2\x2b\x04\x50\x7b\x47\x99\x1a\xcb\xaa\xb4\x0b\xd7\x44\x07\x00\xe9\xe4\xbc\xee\xe6\xb8\x5b\xa3\xb1\x0e\x77\xd1\x41\x66\x3c\x1c\xcd\x37\x6b\x68\x22\x57\x90\x46\xc6\xa3\x70\xb7\x0e\x5d\x84\x1b\x89\xa4\x6f\x08\xd7\x57\xe1\xd8\x4b\xb0\xb2\x04\x7b\x0d\x5d\x1a\x48\xf7\x70\xd8\x36\x36\xd8\x59\xea\xab\x70\x60\xf8\xda\x69\x9d\x11\x34\xf3\xf2\x68\xd6\xa7\x35\x66\x84\xac\x87\x0f\x01\xf4\xa3\xd2\x13\x50\x4c\xba\x75\x9b\xf1\x86\xa5\x2a\x2c\x55\x9d\x71\xee\xb8\x3f\x12\xa0\x3d\xf5\x1d\xe5\x9f\x53\x4a\x04\xe8\x72\x0e\xc9\x12\x2b\x5b\x5a\xc9\x1a\x10\x44\x69\xaf\x73\xe9\x45\xe9\x1b\x00\xc2\x4d\x36\x63\x66\x07\x00\x01\xf0\xe9\x64\x1f\xd6\xb9\xb4\x2b\x7d\x03\xe2\x53\x38\x97\xd6\xb3\x67\x34\x29\x25\xb3\x9d\xa5\xad\xcd\x0c\x20\x0b\xfb\x3c\xc8\xea\x4a\xeb\xee\xd6\x1b\xa0\x4a\x57\xbb\x75\x86\xc7\x1e\x77\x03\xaa\xf4\x19\xc8\x21\x09\x8e\x4a\x4f\x40\xd3\x53\xe3\x63\x2c\x6f\x4a\x6b\x4a\x5d\x84\x6b\x66\xaf\x3d\xa5\x04\x41\x1c\x54\xe4\x48\x5f\x8f\x11\x6e\x00\xe8\x10\x88\xa7\xb3\xb7\xb1\x06\xa8\x35\x64\x4b\x7e\x90\x6f\x00\x68\xb6\x11\x7c\xa9\x01\xd4\x1a\x4a\xeb\xf6\xe2\x03\x26\x42\x7a\x6e\x67\xfb\x89\x24\x98\x08\xd9\x98\x6f\x7f\xb5\x32\x11\x4e\xd6\xc0\x37\x40\x35\xfd\x80\xef\x29\x35\xe3\x56\xd8\x4e\x86\x26\x42\x7f\x26\xe2\xf1\x04\x10\xa5\x21\x3c\xc7\xe8\xb2\x53\xf8\x03\xad\x00\x62\x8d\x6c\x24\xb4\x3d\x8d\x3a\x66\xf0\x7c\x12\x78\x44\x40\xf5\x52\xd0\x77\xab\x17\xf3\xe1\x3a\x66\xd2\x69\x37\xb7\x80\x68\x0e\x5f\x70\x9a\xde\x5a\x83\xf5\x12\x6e\x5d\x63\x22\xa8\x97\xfa\x71\x7a\x19\x40\x31\xd1\xdf\xec\x8d\xab\x97\xbe\xd3\x71\xa8\x1b\xca\xca\xd2\x6b\x4a\xfd\x00\xc0\x17\xc0\xe2\x25\x3c\x1d\x25\x2d\x00\x0c\xad\xff\x89\x60\xdf\xa2\xff\xdd\xa2\xfd\x87\x9f\x9f\x03\xfe\x02\xbb\x93\xb5\xc0\xcd\x11\x00\x00")
+
+func wmm2015v1CofBytes() ([]byte, error) {
+	return bindataRead(
+		_wmm2015v1Cof,
+		"WMM2015v1.COF",
+	)
+}
+
+func wmm2015v1Cof() (*asset, error) {
+	bytes, err := wmm2015v1CofBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "WMM2015v1.COF", size: 4557, mode: os.FileMode(420), modTime: time.Unix(1792203784, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _wmm2015v2Cof = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x95\x57\x5d\x9a\x1c\x21\x08\x7c\xcf\x29\xbc\x80\x1d\xc1\xff\x43\xe4\x39\x37\xc9\xf9\x63\x37\x08\xd8\xba\x93\xec\x7e\xf3\xd0\xd3\x63\x49\x15\x05\xe8\x3a\xe7\x1c\x06\xc8\x57\x70\xe6\xef\xf7\xaf\x5f\xfe\x7e\xfb\x07\xe9\x7b\xe8\x3f\xa1\xfd\x1c\x6f\xda\x0f\xe7\x60\x7c\x77\xce\x63\x4f\xb1\x5d\xbc\xc0\x05\xdd\xa0\xea\xe3\x78\x4b\x80\xf1\x71\x1e\x52\x8f\x57\xbe\xdf\xa7\xda\xcb\x15\xe7\xaa\x3e\x01\x3e\x86\x0b\x07\x00\x9f\x08\x23\x44\x4a\x89\x00\x36\x82\x07\x78\x47\x40\x8a\xe0\x62\x80\x74\xd5\x07\xda\x12\x5e\x89\x17\xf9\x32\x79\x0e\xd6\x57\x21\xc0\xf3\x06\x4a\xe5\xe8\xbe\x0c\x35\x4d\xb7\x8d\x33\x58\xbd\xe2\x00\x44\xa2\xe4\x20\x66\x90\x65\x46\xb4\x06\x63\x4a\x91\x45\xe3\x0d\x28\xcc\x3b\x3e\xe4\x9e\x2f\x59\x1e\x5d\xb9\x32\x01\x88\x12\x62\x24\x80\xc3\x54\x44\xfe\x88\x30\x83\xf9\x70\x35\x02\x10\xc9\xdc\x90\xe8\xfa\x1c\xeb\xe4\x61\xb3\xe4\xf1\xa1\x94\x58\x83\xeb\xa1\xee\x69\x7d\xb6\x5d\x35\x24\x4e\xab\x6b\x23\xad\xf4\x1b\xb6\x28\xc6\x0d\x40\xd7\xc7\x44\x00\x4a\x34\x8c\xb4\x35\xa2\xd1\x1a\xab\x79\x7c\x10\x39\xf9\xd1\x90\x58\x83\x8f\x31\xf3\x32\x68\x41\x53\x93\xa5\xc0\x5c\x9c\x00\x16\x58\x3a\x2d\x1b\x50\xa3\x21\xe9\x63\x7c\xd2\x9a\x59\xf4\x30\x02\x85\xee\x22\x3a\xae\xa2\xf3\x14\x1d\x4b\xb8\xe8\xc9\x0d\x1f\xba\xae\x2a\xfa\x88\x04\x60\xd1\x1d\x98\x39\x74\x55\x6a\xd3\x8a\x4f\x2d\xe5\x29\x1a\x12\xb0\x71\x00\xdd\x46\x00\xc5\x32\xa5\x44\xcb\x72\x9d\x09\x81\xa2\xc5\x07\x36\x4b\x1c\x21\x4b\x37\x32\xa5\x60\x88\x83\xb6\x46\x78\xfa\xa1\xcc\xd2\xb8\xd3\x9a\xfe\xa3\x34\xca\xcc\x92\x2b\x33\xc2\x98\x18\x96\x78\x36\xbd\x44\x00\x26\x59\x71\xa6\x7c\x38\xd2\x14\x20\x58\x78\x8c\x2b\x92\xa5\xd1\xb3\xfc\x5b\x96\xa7\x5b\x43\x51\x00\x12\x20\x31\x91\xc6\x1a\xfc\x20\x67\xb6\x35\xc6\x25\x02\x30\x49\x88\xf2\x5b\xd3\x08\xc1\x8e\x19\x8e\x40\xcb\x7c\x95\xb2\x29\xa0\xc6\x19\x1f\xe0\x11\x5d\x25\xad\x0d\xa4\xa8\x3f\x15\x5f\x9d\x69\xf5\x35\xf3\xbe\x3e\x27\xdb\x71\x68\xea\x90\x00\x73\xb4\x4d\xa9\x7e\xd4\x52\x3e\x46\xc8\x04\xe0\x37\x19\x65\xb3\x62\x95\xf6\x75\xcc\x54\xe9\x38\x3d\x25\x30\x59\x7b\x8d\xe9\x48\x80\x2c\x93\x1d\xa4\x2e\x0d\x25\x63\x1c\x10\xa0\x48\xcf\x06\xb6\xb0\xea\xa8\x0c\x4b\x6b\x10\xa0\xca\x80\xe8\x32\xe3\xcc\xaa\xba\x1a\xd7\xc4\x87\xc1\x1c\x4f\x3e\xc0\xea\x43\x93\xf2\x1e\x05\xc1\xfb\xc2\xb2\x0a\xd7\xc9\xd7\xa6\x0f\x1e\xe6\xac\x18\xa3\xef\x83\x71\x4d\x7c\x18\xa2\x41\xca\xd0\xba\x65\xd8\x11\x20\x49\x9f\x71\xc7\x41\x5a\x56\x6d\x11\xf2\x7b\x5f\x28\x96\x47\x5a\x8d\x6b\xe2\x03\x68\xaf\x2c\xa5\x61\x00\x99\x00\x55\x44\x4f\x35\xdd\xa6\x06\xd6\xe2\x6b\xcf\x87\xed\x82\xd3\xd1\x99\x56\xa7\xbb\x18\x37\x9c\xce\xff\x61\x5c\xb7\xc6\x35\x0e\x05\xc7\x31\x43\x73\xa9\x4b\x03\xb9\x59\x7c\xb7\xd3\xa6\xf8\xc2\x46\x49\x8d\x43\xc9\x57\x3b\x66\x29\x11\x20\xbd\xcf\x8e\x71\x12\x1a\x4a\x69\x1d\x95\x7d\x1a\xe7\x41\x22\xf8\xe5\x04\xda\x29\x95\x4d\x5f\xb5\x80\xb8\x1e\x28\x5d\x1b\xa8\x89\xd4\xf5\x56\xb5\x16\x5f\x57\xe3\xa4\xa7\x87\xfc\x7e\xcc\x52\x26\xc0\xec\x02\xd5\xd7\xbe\x98\x4b\xa3\xf8\x20\x88\xd3\x5e\x6f\x3a\xe1\x44\x89\x9c\xbe\x01\x20\xb9\xd1\x31\x13\x8f\x94\x18\x80\xef\xf6\xf5\x8b\x5b\xb0\xa4\xf5\x06\xc4\xed\xd0\x4f\xcb\xf1\xbf\x34\xd0\x0d\x48\xdb\xf9\x97\x3e\x47\xc8\x92\xfd\x26\x73\xbc\xef\x53\xc3\x0b\x40\x9d\xae\xfb\x40\x7d\x97\xc6\x0d\xa8\xd2\x67\x28\x97\x24\x3c\xf6\x03\x67\xa9\xbd\x1b\xd3\xcc\xd6\x23\xa5\x2e\xe3\xbc\xe9\x60\x3f\x8a\xa6\x2c\x81\xdc\xf7\xa5\x1f\x9a\xed\x87\xb0\x52\x02\x1d\x02\xf1\x50\x1a\xbb\xd3\xa0\xa5\x21\x37\x1d\xff\xa9\x96\x40\x4f\x51\xb9\x9c\x38\x5c\x52\xb3\xa6\x15\xb4\x34\x64\x99\x5f\x6e\x86\x7b\x84\xf4\xbe\x4b\xad\x59\xda\x23\xe4\xad\xf8\x96\xb9\x04\x5b\x96\x4e\xa5\x81\xc7\x9e\x66\x40\x7d\x9f\xe1\xfe\x1f\xa2\x9b\x54\x6b\xd5\xcb\xda\x27\xe3\xfa\x9b\xc8\x88\x50\x8e\x1a\x28\x02\x84\xf7\x18\xf5\x78\x72\x5a\x22\xc0\x76\xc3\x30\x16\xee\x11\xf0\xbb\x63\x06\xb5\x96\x34\x4b\x5f\x8d\x4a\x06\xe0\x76\x86\x87\x0f\x73\x09\xb5\x96\xf4\x02\xb9\x1c\x28\x61\xd3\x90\xde\x57\x23\x8f\x5f\x1c\xbb\x0c\xc8\xdb\xf5\xee\x48\x49\x35\x94\xed\x7a\xb7\xdc\xaa\x76\x0d\x75\x3b\xe3\xc2\xa9\x96\x34\x42\xdb\x46\xf0\x7a\x1d\xde\x22\xf4\x6d\xb6\x2e\xc3\x68\x03\x98\x5a\xc2\xd3\x7f\xac\x1b\x25\x80\x2d\xad\xff\x88\x80\xdb\x6f\xe1\x8b\xbb\x06\xfc\xe8\xdf\xfc\xfb\x3e\xe0\x2f\x44\x26\x8f\xd3\xcd\x11\x00\x00")
+
+func wmm2015v2CofBytes() ([]byte, error) {
+	return bindataRead(
+		_wmm2015v2Cof,
+		"WMM2015v2.COF",
+	)
+}
+
+func wmm2015v2Cof() (*asset, error) {
+	bytes, err := wmm2015v2CofBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "WMM2015v2.COF", size: 4557, mode: os.FileMode(420), modTime: time.Unix(1792203784, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _wmm2020Cof = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x95\x57\x4b\x96\xdc\x30\x08\xdc\xe7\x14\xba\x80\x1c\x81\xfe\x87\x98\x75\xee\x7f\x93\xc8\x0d\x02\x24\xcb\x93\xcc\xbc\x59\xb8\xdb\x2e\xa0\x8a\x02\xb9\x9d\x73\x0e\x03\x86\x2b\x38\xf3\xf7\xe7\xeb\xcb\xdf\xdf\xce\xcf\x80\xbf\x21\xfc\xc6\x00\xfd\xd7\xf8\xe0\xdc\xb8\xe1\xb1\xa7\x90\xae\xcc\x4f\x98\x00\xe5\xaa\x4e\xbf\x25\xc0\xf8\x77\x1e\x52\x0e\x74\x2f\x95\x8c\x57\x9f\x4f\xd5\x09\xf0\x98\x2f\x18\x00\xfc\x64\xb8\x3f\x06\x8d\x2b\x57\x1e\x40\xd2\x72\x06\xa4\x0c\x0e\x7b\xc3\x8b\xa0\xbd\xc3\x55\xf8\x21\x5f\x2f\xe0\xab\x18\x2e\x24\x00\x7e\x88\x95\x5a\xae\x46\xcf\xc4\x44\x57\x1f\xf8\x85\xf3\x2a\x5e\x37\xe9\x48\x25\x39\x88\x25\x4a\xe5\x86\x34\x0a\x96\x4b\x8a\x4c\x1a\x63\x03\x7e\xcc\x37\x09\xeb\x7c\x91\x4b\x97\xaf\x4a\x00\x2a\x09\xe3\xbc\x87\x09\x34\x6c\xbc\xd2\xc4\xc2\xcc\x10\x09\x3f\x64\xfb\x28\xe8\x73\x12\x59\x3d\x68\x32\x07\x1f\x59\x13\x73\x70\x3d\xc4\x29\x88\xe1\x30\xc2\xc2\xca\x21\xb1\xac\xae\x85\xce\xd9\x91\x05\x66\x40\x51\x00\x12\x80\x73\x36\xe6\xe0\x21\x37\x2d\xbc\x58\x97\x74\x02\x44\xee\xcb\xcc\x00\xbd\x2b\xe9\x2c\xd8\xc1\xbf\x12\x80\xbf\x49\x95\xa8\xfa\x38\x5c\x35\x0b\xf7\x59\xac\x31\x2e\xcb\x00\x64\x26\x3d\x1a\x91\x24\x98\x21\x1d\xae\xb8\x92\xce\x93\xf4\xdd\x69\x90\x5c\xc6\xd3\x86\x34\x10\x80\x48\x43\xab\x5c\x39\x06\x43\x3a\x28\x16\xaf\x4c\x00\x22\x0d\x89\xef\x8d\x66\x81\xad\x03\x14\xdb\x09\x90\x58\x4c\x98\x4d\x8d\x4b\x7b\x51\x55\x62\x0e\x2c\x03\xc4\x99\x7d\xc8\x0a\x0a\x08\x9a\xec\x2e\xa9\x4c\x6b\xb8\x92\x0f\xf6\xf6\x0b\xe9\x40\x00\x10\x40\x61\x3b\x74\x5b\x78\x5a\x55\x2a\x62\x8d\x1a\x67\xdc\xe1\x5b\x5b\x87\xda\xaa\x11\x20\x8a\x36\x7c\x6f\x6c\x8d\xaa\x1c\xcc\x3c\x24\x02\x24\x1e\xf2\x39\x40\xbe\xa4\xe5\x29\x4d\xd6\x09\xa0\x2a\xcd\xec\xdd\x92\x0e\x0f\x0e\x45\xe2\x72\x21\xa5\xd9\xa9\x69\x46\xe1\x01\xa8\x22\x6b\x53\x05\x17\x59\xb7\x89\xab\x53\x56\x3f\xf7\xd2\xf0\x31\x58\x2f\xc5\xb5\x71\x55\x64\xf5\x8d\xef\x79\x28\xba\xc8\x96\x0c\x85\x00\x1c\x22\x17\x21\x8d\x36\x6c\x35\xc6\x25\x00\xa7\x87\x3c\xe3\x62\xb4\x1b\x18\x15\x80\x04\xc8\x32\xe4\x69\x5f\xa8\xf7\x53\xa6\xd3\x0c\x28\xb2\xa8\xb9\x71\x58\x2d\xa0\xad\x6b\xa6\x7e\xfe\xb9\x5d\x4d\x62\xf5\xa3\xbd\xe3\x00\x34\xe9\xc3\xa8\xfc\x7f\xfa\xd0\xc4\xde\x9a\xc1\xb5\xc5\xd3\xa6\x25\x04\xe0\xca\xa1\x32\xbf\x31\xad\x22\xeb\x9a\xa1\x12\x20\xee\xb3\x02\xcb\x29\x92\x57\x59\x9b\xd8\x1b\xe7\xa2\xbe\x4f\xc2\x73\xa7\x33\x01\xb2\x34\x8e\x73\x41\x52\x95\xc2\x62\x2b\x02\x94\x7d\x6b\xb8\x68\x27\xdf\x94\xc4\x19\xaa\x58\x2e\xcb\x8e\x37\x19\x4c\x1f\x12\x01\xda\xc3\xc9\x0b\xe9\x6d\x6b\x74\x69\x9c\xcb\xcf\x37\x81\x43\xe3\xba\x36\xae\x89\x97\xa2\xed\x03\xae\xa4\xbb\x0c\x90\x93\xb3\x13\xc0\xb6\x37\xac\xe6\xeb\xda\x38\x1d\xcc\x7e\xe2\xe0\x89\x74\x97\x01\xd2\x13\x76\x1c\x4e\x70\x9c\x69\x06\x4c\x07\x49\xe5\xe6\x7d\xe1\xb9\x97\xba\x34\xce\x69\xe5\xd5\x96\x64\xf8\xb3\x4a\x55\x54\xea\xa7\xc2\xc3\x6a\xbe\xae\x8d\xeb\x97\xd2\xcf\xc7\x92\x32\x01\xba\x78\xb4\x8b\x4a\xf5\x78\x3e\x8c\x0c\x10\xa4\xd3\x66\x92\x97\xb0\x61\xe9\xf4\x0d\x80\x87\x36\xf1\x85\x03\x03\x70\xb7\xcd\xea\x87\x55\xd6\x1b\x10\x45\x56\x9d\x87\xf3\xe6\x8b\x04\x48\x7a\x70\xf3\xbd\xf4\x32\xa2\x9c\x21\xef\xaf\x15\x63\x8f\x97\xa3\x5b\x59\xa5\xb2\x67\xf0\xe1\xc5\xad\x9c\xa1\x0a\x87\x09\x48\x36\x2c\x3c\x54\x6a\xfb\x09\xeb\x17\x59\x2d\x96\x00\x5d\x96\x7c\x3a\x85\x85\xbd\xd3\x10\x24\x6e\x17\xd2\xed\xb5\x71\xa0\x4b\x20\x9e\x97\xc0\x66\x0d\x50\x6b\x80\x29\x29\xbc\x92\x06\xb5\x06\x9a\x43\xb1\xbc\xca\x0a\x6a\x0d\x4b\x3a\xbf\xba\x15\x0e\xd6\xf0\xc7\x89\x63\x95\xc0\x5a\x23\x3e\xde\x3d\x8f\x1c\xca\xfe\xc6\x69\xba\x75\x2c\xa9\x3e\xe6\x01\xec\x88\x3e\x49\x3f\xad\x01\xdf\x97\xd4\xf7\x17\x48\x1f\x5f\xfa\x40\x19\x20\xec\xc3\xe5\xf1\x7d\xdd\xdf\x00\x10\x6b\x80\x00\xde\x4b\x42\x5d\x33\x78\xf0\xd2\x53\x25\x54\x2f\x59\x95\xf0\xd5\xad\xa8\x07\x4a\x30\x3f\x8f\xf3\xab\x5b\xd1\xae\x99\xe7\xd5\x76\xdc\x11\x20\xed\x85\x78\x38\x0d\x90\x13\x40\x7e\xbc\xde\x1d\xb7\x86\x72\x28\x07\xf3\xd5\xef\x54\xaa\x3b\x55\xff\x8f\x0c\xed\xe1\xd1\xf5\x57\xc6\x83\x43\x7f\xcc\x59\xf8\xb6\x0f\xc6\x4b\xf0\x58\xcb\x47\x00\x3c\xce\xe9\xef\xd6\x0c\x7e\xaa\xda\xce\xf0\xa5\xd3\x8b\xbd\xfb\x0f\xff\x7e\x0e\xf8\x0b\x6a\x45\xe1\x94\xcd\x11\x00\x00")
+
+func wmm2020CofBytes() ([]byte, error) {
+	return bindataRead(
+		_wmm2020Cof,
+		"WMM2020.COF",
+	)
+}
+
+func wmm2020Cof() (*asset, error) {
+	bytes, err := wmm2020CofBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "WMM2020.COF", size: 4557, mode: os.FileMode(420), modTime: time.Unix(1792203784, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // getAsset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -103,5 +166,8 @@ func getAsset(name string) ([]byte, error) {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"WMM.COF": wmmCof,
+	"WMM.COF":       wmmCof,
+	"WMM2015v1.COF": wmm2015v1Cof,
+	"WMM2015v2.COF": wmm2015v2Cof,
+	"WMM2020.COF":   wmm2020Cof,
 }
@@ -4,55 +4,133 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
 )
 
 const (
-	MaxLegendreOrder = 12
+	MaxLegendreOrder = 12 // Degree and order of the standard WMM coefficients
 )
 
 var (
-	Epoch     DecimalYear // The Epoch of the loaded coefficients file, e.g. 2015.0
-	COFName   string      // The filename of the loaded COF file
-	ValidDate time.Time   // The beginning valid date of the loaded COF file
+	Epoch        DecimalYear // The Epoch of the loaded coefficients file, e.g. 2015.0
+	COFName      string      // The filename of the loaded COF file
+	ValidDate    time.Time   // The beginning valid date of the loaded COF file
+	defaultModel *Model
+	defaultMu    sync.RWMutex
+)
+
+// Model represents a single set of WMM coefficients as loaded from a COF file.
+//
+// Several Models can be used side by side, e.g. to reprocess archived data
+// with the model that was current at the time.
+// The package-level functions GetWMMCoefficients and CalculateWMMMagneticField
+// use the default Model, which is the one most recently loaded by LoadWMMCOF.
+//
+// The error model is set from the model name in the header of the COF file.
+// Models without a published error model, e.g. custom ones or releases newer
+// than this package, are given the zero ErrorModel, so that the uncertainties
+// of their fields are unknown and reported as NaN.  It may be replaced before
+// the Model is used.
+//
+// A Model is safe for concurrent use by multiple goroutines.
+type Model struct {
+	Epoch     DecimalYear // The Epoch of the coefficients file, e.g. 2015.0
+	COFName   string      // The model name given in the coefficients file header
+	ValidDate time.Time   // The beginning valid date of the coefficients file
+	Errors    ErrorModel  // The error model published with the coefficients, zero if unknown
 	cGnm      [][]float64
 	cHnm      [][]float64
 	cDGnm     [][]float64
 	cDHnm     [][]float64
-)
+	nMax      int         // Degree of the main field coefficients
+	nMaxSV    int         // Degree of the secular variation coefficients
+	gnm       [][]float64 // G(n,m) at ValidDate
+	hnm       [][]float64 // H(n,m) at ValidDate
+	mu        sync.Mutex // Guards the cached field below
+	cached    bool
+	curLoc    egm96.Location // Spherical
+	curField  MagneticField
+}
+
+// DefaultModel returns the Model used by the package-level functions.
+func DefaultModel() *Model {
+	defaultMu.RLock()
+	w := defaultModel
+	defaultMu.RUnlock()
+	if w==nil {
+		_ = LoadWMMCOF("")
+		defaultMu.RLock()
+		w = defaultModel
+		defaultMu.RUnlock()
+	}
+	return w
+}
 
 // GetWMMCoefficients calculates the spherical harmonic coefficients G(n,m), H(n,m)
 // and their rates of change dG(n,m), dH(n,m) at the input time.
 //
 // If the request n,m are invalid or the requested time is outside of the range
 // of validity of the loaded coefficients file, it will return an error.
+// In the latter case the error is a *DateError and the coefficients are still
+// returned.
+//
+// The coefficients are those of the default Model.
 func GetWMMCoefficients(n, m int, t time.Time) (gnm, hnm, dgnm, dhnm float64, err error) {
-	if Epoch==0 {
-		_ = LoadWMMCOF("")
-	}
-	if n<0 || n>MaxLegendreOrder || m<0 || m>MaxLegendreOrder {
+	return DefaultModel().Coefficients(n, m, t)
+}
+
+// Coefficients calculates the spherical harmonic coefficients G(n,m), H(n,m)
+// and their rates of change dG(n,m), dH(n,m) of the Model at the input time.
+//
+// If the request n,m are invalid or the requested time is outside of the range
+// of validity of the Model, it will return an error.
+func (w *Model) Coefficients(n, m int, t time.Time) (gnm, hnm, dgnm, dhnm float64, err error) {
+	if n<0 || n>w.nMax || m<0 || m>w.nMax {
 		return 0, 0, 0, 0, fmt.Errorf("n, m = (%d,%d) must be between 0 and %d",
-			n, m, MaxLegendreOrder)
+			n, m, w.nMax)
 	}
 	if m>n {
 		return 0, 0, 0, 0, fmt.Errorf("m=%d must be less than n=%d", m, n)
 	}
-	if t.Sub(ValidDate) < 0 || TimeToDecimalYears(t)>Epoch+5 {
-		err = fmt.Errorf("requested date %v is outside of validity period beginning %v of WMM.COF file",
-				t, ValidDate)
-	}
-	dt := float64(TimeToDecimalYears(t)- Epoch)
-	gnm = cGnm[n][m] + dt*cDGnm[n][m]
-	hnm = cHnm[n][m] + dt*cDHnm[n][m]
-	dgnm = cDGnm[n][m]
-	dhnm = cDHnm[n][m]
+	err = w.checkDate(t)
+	dt := float64(TimeToDecimalYears(t)- w.Epoch)
+	gnm = w.cGnm[n][m] + dt*w.cDGnm[n][m]
+	hnm = w.cHnm[n][m] + dt*w.cDHnm[n][m]
+	dgnm = w.cDGnm[n][m]
+	dhnm = w.cDHnm[n][m]
 	return gnm, hnm, dgnm, dhnm, err
 }
 
-// LoadWMMCOF loads the specified coefficients file.
+// MaxDegree returns the maximum degree (and order) n of the main field
+// coefficients of the Model, e.g. 12 for the standard WMM.
+func (w *Model) MaxDegree() (n int) {
+	return w.nMax
+}
+
+// SVDegree returns the maximum degree n of the secular variation coefficients
+// of the Model, i.e. the highest degree with a nonzero rate of change.
+// This may be lower than MaxDegree for high-degree models.
+func (w *Model) SVDegree() (n int) {
+	return w.nMaxSV
+}
+
+// checkDate returns a *DateError if the requested time is outside of the range
+// of validity of the Model.
+func (w *Model) checkDate(t time.Time) (err error) {
+	if t.Sub(w.ValidDate) < 0 || TimeToDecimalYears(t)>w.Epoch+5 {
+		return &DateError{Date: t, Start: w.ValidDate, End: (w.Epoch+5).ToTime(), Model: w.COFName}
+	}
+	return nil
+}
+
+// LoadWMMCOF loads the specified coefficients file into the default Model.
 //
 // It populates the internal coefficient values representing G(n,m), H(n,m), DG(n,m), DH(n,m),
 // Epoch, COFName, and ValidDate.
@@ -61,11 +139,24 @@ func GetWMMCoefficients(n, m int, t time.Time) (gnm, hnm, dgnm, dhnm float64, er
 // The default coefficients file is currently WMM2020.COF, valid from
 // 12/10/2019 until 12/31/2024.
 func LoadWMMCOF(fn string) (err error) {
-	var (
-		data []byte
-		epoch float64
-		n, m  int
-	)
+	w, err := LoadModel(fn)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultModel = w
+	Epoch = w.Epoch
+	COFName = w.COFName
+	ValidDate = w.ValidDate
+	defaultMu.Unlock()
+	return nil
+}
+
+// LoadModel returns a new Model loaded from the specified coefficients file.
+//
+// If the passed filename is "", it loads the default (current) coefficients file.
+func LoadModel(fn string) (w *Model, err error) {
+	var data []byte
 
 	if fn=="" {
 		data, err = getAsset("WMM.COF")
@@ -73,69 +164,108 @@ func LoadWMMCOF(fn string) (err error) {
 		data, err = ioutil.ReadFile(fn)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return parseCOF(data, fn)
+}
+
+// ReadModel returns a new Model read from the coefficients in WMM COF format
+// provided by r.
+func ReadModel(r io.Reader) (w *Model, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseCOF(data, "")
+}
+
+func parseCOF(data []byte, fn string) (w *Model, err error) {
+	var (
+		epoch float64
+		n, m  int
+	)
 
+	w = new(Model)
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	// Read and parse header
 	if !scanner.Scan() {
-		return fmt.Errorf("Could not read header line in WMM coefficient file %s", fn)
+		return nil, fmt.Errorf("Could not read header line in WMM coefficient file %s", fn)
 	}
 	dat := strings.Fields(scanner.Text())
+	if len(dat)<3 {
+		return nil, fmt.Errorf("bad header in WMM coefficient file %s", fn)
+	}
 	if epoch, err = strconv.ParseFloat(dat[0], 64); err != nil {
-		return fmt.Errorf("bad header epoch date in WMM coefficient file %s", fn)
+		return nil, fmt.Errorf("bad header epoch date in WMM coefficient file %s", fn)
 	}
-	Epoch = DecimalYear(epoch)
-	COFName = dat[1]
-	if ValidDate, err = time.Parse("01/02/2006", dat[2]); err != nil {
-		return fmt.Errorf("bad header valid date in WMM coefficient file %s", fn)
+	w.Epoch = DecimalYear(epoch)
+	w.COFName = dat[1]
+	w.Errors = errorModelFor(w.COFName)
+	if w.ValidDate, err = time.Parse("01/02/2006", dat[2]); err != nil {
+		return nil, fmt.Errorf("bad header valid date in WMM coefficient file %s", fn)
 	}
 
-	cGnm = make([][]float64, MaxLegendreOrder+1)
-	cGnm[0] = []float64{0}
-	cHnm = make([][]float64, MaxLegendreOrder+1)
-	cHnm[0] = []float64{0}
-	cDGnm = make([][]float64, MaxLegendreOrder+1)
-	cDGnm[0] = []float64{0}
-	cDHnm = make([][]float64, MaxLegendreOrder+1)
-	cDHnm[0] = []float64{0}
-
-	// Read and parse testdata
-	curN := 0
+	// Read and parse coefficients, sizing the model from the highest degree found.
+	// Lines may omit the secular variation columns, e.g. for the crustal
+	// part of high-degree models.
 	for scanner.Scan() {
 		s := strings.Fields(scanner.Text())
-		if len(s)<6 {
+		if len(s)<4 {
 			continue
 		}
 		if n, err = strconv.Atoi(s[0]); err!=nil {
-			return fmt.Errorf("bad n value in WMM coefficient file %s", fn)
+			return nil, fmt.Errorf("bad n value in WMM coefficient file %s", fn)
 		}
 		if m, err = strconv.Atoi(s[1]); err!=nil {
-			return fmt.Errorf("bad m value in WMM coefficient file %s", fn)
+			return nil, fmt.Errorf("bad m value in WMM coefficient file %s", fn)
+		}
+		if n<1 || m<0 || m>n {
+			return nil, fmt.Errorf("bad n, m = (%d,%d) in WMM coefficient file %s", n, m, fn)
+		}
+		if n>w.nMax {
+			w.nMax = n
+			w.cGnm = growDegree(w.cGnm, n)
+			w.cHnm = growDegree(w.cHnm, n)
+			w.cDGnm = growDegree(w.cDGnm, n)
+			w.cDHnm = growDegree(w.cDHnm, n)
+		}
+		if w.cGnm[n][m], err = strconv.ParseFloat(s[2], 64); err != nil {
+			return nil, fmt.Errorf("bad Gnm value in WMM coefficient file %s", fn)
 		}
-		if n>curN {
-			cGnm[n] = make([]float64, n+1)
-			cHnm[n] = make([]float64, n+1)
-			cDGnm[n] = make([]float64, n+1)
-			cDHnm[n] = make([]float64, n+1)
-			curN = n
+		if w.cHnm[n][m], err = strconv.ParseFloat(s[3], 64); err != nil {
+			return nil, fmt.Errorf("bad Hnm value in WMM coefficient file %s", fn)
 		}
-		if cGnm[n][m], err = strconv.ParseFloat(s[2], 64); err != nil {
-			return fmt.Errorf("bad Gnm value in WMM coefficient file %s", fn)
+		if len(s)<6 {
+			continue
 		}
-		if cHnm[n][m], err = strconv.ParseFloat(s[3], 64); err != nil {
-			return fmt.Errorf("bad Hnm value in WMM coefficient file %s", fn)
+		if w.cDGnm[n][m], err = strconv.ParseFloat(s[4], 64); err != nil {
+			return nil, fmt.Errorf("bad DGnm value in WMM coefficient file %s", fn)
 		}
-		if cDGnm[n][m], err = strconv.ParseFloat(s[4], 64); err != nil {
-			return fmt.Errorf("bad DGnm value in WMM coefficient file %s", fn)
+		if w.cDHnm[n][m], err = strconv.ParseFloat(s[5], 64); err != nil {
+			return nil, fmt.Errorf("bad DHnm value in WMM coefficient file %s", fn)
 		}
-		if cDHnm[n][m], err = strconv.ParseFloat(s[5], 64); err != nil {
-			return fmt.Errorf("bad DHnm value in WMM coefficient file %s", fn)
+		if (w.cDGnm[n][m]!=0 || w.cDHnm[n][m]!=0) && n>w.nMaxSV {
+			w.nMaxSV = n
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	if w.nMax<1 {
+		return nil, fmt.Errorf("no coefficients found in WMM coefficient file %s", fn)
+	}
+
+	dt := float64(TimeToDecimalYears(w.ValidDate) - w.Epoch)
+	w.gnm = make([][]float64, w.nMax+1)
+	w.hnm = make([][]float64, w.nMax+1)
+	for n:=0; n<=w.nMax; n++ {
+		w.gnm[n] = make([]float64, len(w.cGnm[n]))
+		w.hnm[n] = make([]float64, len(w.cHnm[n]))
+		for m := range w.cGnm[n] {
+			w.gnm[n][m] = w.cGnm[n][m] + dt*w.cDGnm[n][m]
+			w.hnm[n][m] = w.cHnm[n][m] + dt*w.cDHnm[n][m]
+		}
+	}
+	return w, nil
 }
@@ -0,0 +1,148 @@
+package wmm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// DipPole is the location of a magnetic dip pole at a given time.
+type DipPole struct {
+	Time     time.Time
+	Location egm96.Location
+}
+
+// GetWMMDipPoles returns the locations of the north and south magnetic dip
+// poles of the default Model at the input time.
+// See Model.DipPoles.
+func GetWMMDipPoles(t time.Time) (north, south egm96.Location, err error) {
+	return DefaultModel().DipPoles(t)
+}
+
+// DipPoles returns the locations on the WGS84 ellipsoid of the north and
+// south magnetic dip poles of the Model at the input time, where the field
+// is vertical, i.e. H = 0 and the inclination I is ±90º.
+//
+// Starting from the weakest H found on a grid over each polar cap, the
+// location is found by Newton's method using the spatial gradient of the
+// field, and is accurate to well under a meter.
+//
+// As for MagneticField, an informational *DateError is returned if the
+// requested time is outside the validity period of the Model, but the
+// poles are still calculated.
+// An error is returned if the iteration does not converge.
+func (w *Model) DipPoles(t time.Time) (north, south egm96.Location, err error) {
+	dateErr := w.checkDate(t)
+	lat, lng := w.weakestHIn(1, t)
+	if north, err = w.dipPole(lat, lng, t); err != nil {
+		return north, south, err
+	}
+	lat, lng = w.weakestHIn(-1, t)
+	if south, err = w.dipPole(lat, lng, t); err != nil {
+		return north, south, err
+	}
+	return north, south, dateErr
+}
+
+// GetWMMDipPolePath returns the paths of the north and south magnetic dip
+// poles of the default Model from start to end.
+// See Model.DipPolePath.
+func GetWMMDipPolePath(start, end time.Time, years, months, days int) (north, south []DipPole, err error) {
+	return DefaultModel().DipPolePath(start, end, years, months, days)
+}
+
+// DipPolePath returns the paths of the north and south magnetic dip poles
+// of the Model from start to end, inclusive, at steps given in years,
+// months and days as for time.AddDate, e.g. 0, 1, 0 for monthly positions.
+//
+// Each position is found starting from the previous one, so this is much
+// faster than calling DipPoles at each time.
+// As for DipPoles, an informational *DateError is returned if any of the
+// times is outside the validity period of the Model.
+func (w *Model) DipPolePath(start, end time.Time, years, months, days int) (north, south []DipPole, err error) {
+	if !start.AddDate(years, months, days).After(start) {
+		return nil, nil, fmt.Errorf("dip pole path step of %d years, %d months, %d days must be positive",
+			years, months, days)
+	}
+	if end.Before(start) {
+		return nil, nil, fmt.Errorf("dip pole path end %v is before start %v", end, start)
+	}
+
+	n, s, err := w.DipPoles(start)
+	if err != nil && !errors.Is(err, ErrDateOutOfRange) {
+		return nil, nil, err
+	}
+	north = append(north, DipPole{start, n})
+	south = append(south, DipPole{start, s})
+	for i:=1; ; i++ {
+		t := start.AddDate(i*years, i*months, i*days)
+		if t.After(end) {
+			break
+		}
+		if e := w.checkDate(t); e != nil && err == nil {
+			err = e
+		}
+		latN, lngN, _ := n.Geodetic()
+		latS, lngS, _ := s.Geodetic()
+		var e error
+		if n, e = w.dipPole(latN/egm96.Deg, lngN/egm96.Deg, t); e != nil {
+			return north, south, e
+		}
+		if s, e = w.dipPole(latS/egm96.Deg, lngS/egm96.Deg, t); e != nil {
+			return north, south, e
+		}
+		north = append(north, DipPole{t, n})
+		south = append(south, DipPole{t, s})
+	}
+	return north, south, err
+}
+
+// dipPole finds the dip pole nearest the input latitude and longitude, in
+// degrees, by Newton's method on the horizontal components X and Y.
+func (w *Model) dipPole(lat, lng float64, t time.Time) (loc egm96.Location, err error) {
+	const (
+		maxSteps = 50
+		maxStep  = 200e3 // Largest step allowed, m
+		tol      = 1e-3  // Step at which the iteration has converged, m
+	)
+
+	for i:=0; i<maxSteps; i++ {
+		loc = egm96.NewLocationGeodetic(lat, lng, 0)
+		m, _ := w.MagneticField(loc, t)
+		x, y, z, _, _, _ := m.Ellipsoidal()
+		g := m.Gradient()
+		_, _, r := loc.Spherical()
+		sinPhi, cosPhi := math.Sincos(lat*egm96.Deg)
+		tanPhi := sinPhi/cosPhi
+
+		// Derivatives of X and Y in nT/m with respect to north and east
+		// displacement, including the turning of the local axes
+		dXdN := g[0][0]/1000 + z/r
+		dXdE := g[0][1]/1000 - y*tanPhi/r
+		dYdN := g[1][0]/1000
+		dYdE := g[1][1]/1000 + (z+x*tanPhi)/r
+		det := dXdN*dYdE - dXdE*dYdN
+		dN := -(dYdE*x - dXdE*y)/det
+		dE := -(dXdN*y - dYdN*x)/det
+		if d := math.Hypot(dN, dE); d>maxStep {
+			dN *= maxStep/d
+			dE *= maxStep/d
+		}
+
+		lat += dN/r/egm96.Deg
+		lng += dE/(r*cosPhi)/egm96.Deg
+		if lat>90 {
+			lat, lng = 180-lat, lng+180
+		} else if lat< -90 {
+			lat, lng = -180-lat, lng+180
+		}
+		lng = math.Mod(lng+540, 360)-180
+		if math.Hypot(dN, dE)<tol {
+			return egm96.NewLocationGeodetic(lat, lng, 0), nil
+		}
+	}
+	return loc, fmt.Errorf("magnetic dip pole search did not converge at %v", t)
+}
@@ -0,0 +1,87 @@
+package wmm
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestDipPoles(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WMM2020 report: the dip poles at 2020.0 are at 86.50ºN, 164.04ºE and 64.07ºS, 135.88ºE
+	tt := DecimalYear(2020).ToTime()
+	north, south, err := w.DipPoles(tt)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	lat, lng, _ := north.Geodetic()
+	testDiff("North dip pole latitude", lat/egm96.Deg, 86.50, 0.01, t)
+	testDiff("North dip pole longitude", lng/egm96.Deg, 164.04, 0.01, t)
+	lat, lng, _ = south.Geodetic()
+	testDiff("South dip pole latitude", lat/egm96.Deg, -64.07, 0.01, t)
+	testDiff("South dip pole longitude", lng/egm96.Deg, 135.88, 0.01, t)
+
+	for _, loc := range []egm96.Location{north, south} {
+		mag, _ := w.MagneticField(loc, tt)
+		testDiff("Dip pole H", mag.H(), 0, 1e-3, t)
+		testDiff("Dip pole |I|", math.Abs(mag.I()), 90, 1e-5, t)
+	}
+
+	if _, _, err = w.DipPoles(DecimalYear(2026).ToTime()); !errors.Is(err, ErrDateOutOfRange) {
+		t.Errorf("%sexpected a date error, got %v%s", red, err, reset)
+	}
+}
+
+func TestDipPolePath(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	north, south, err := w.DipPolePath(start, end, 0, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(north)!=60 || len(south)!=60 {
+		t.Fatalf("expected 60 monthly positions, got %d and %d", len(north), len(south))
+	}
+	if !north[59].Time.Equal(end) {
+		t.Errorf("%sexpected the path to end at %v, got %v%s", red, end, north[59].Time, reset)
+	}
+
+	for _, i := range []int{0, 17, 59} {
+		n, s, _ := w.DipPoles(north[i].Time)
+		for _, p := range []struct {
+			name     string
+			got, exp egm96.Location
+		}{{"north", north[i].Location, n}, {"south", south[i].Location, s}} {
+			lat, lng, _ := p.got.Geodetic()
+			latE, lngE, _ := p.exp.Geodetic()
+			testDiff(p.name+" path latitude", lat/egm96.Deg, latE/egm96.Deg, 1e-6, t)
+			testDiff(p.name+" path longitude", lng/egm96.Deg, lngE/egm96.Deg, 1e-6, t)
+		}
+	}
+
+	// The north dip pole moves about 40-50 km/yr towards Siberia
+	_, _, r := north[0].Location.Spherical()
+	lat0, lng0, _ := north[0].Location.Geodetic()
+	lat1, lng1, _ := north[12].Location.Geodetic()
+	d := r*math.Acos(math.Sin(lat0)*math.Sin(lat1) + math.Cos(lat0)*math.Cos(lat1)*math.Cos(lng1-lng0))
+	if d<30e3 || d>60e3 {
+		t.Errorf("%snorth dip pole moved %4.1f km in a year%s", red, d/1000, reset)
+	}
+
+	if _, _, err = w.DipPolePath(end, start, 0, 1, 0); err == nil {
+		t.Errorf("%sexpected an error for end before start%s", red, reset)
+	}
+	if _, _, err = w.DipPolePath(start, end, 0, 0, 0); err == nil {
+		t.Errorf("%sexpected an error for a zero step%s", red, reset)
+	}
+}
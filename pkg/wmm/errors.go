@@ -0,0 +1,72 @@
+package wmm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Range of heights relative to the WGS84 ellipsoid over which the WMM is valid, in meters.
+const (
+	MinHeight = -1000
+	MaxHeight = 850000
+)
+
+// Sentinel errors for use with errors.Is.
+// The errors actually returned are a *DateError or a *HeightError, which
+// carry the details of the offending value.
+var (
+	ErrDateOutOfRange   = errors.New("date is outside of the validity period of the model")
+	ErrHeightOutOfRange = errors.New("height is outside of the valid range of the model")
+)
+
+// DateError reports a requested date outside of the validity period of a model.
+//
+// The field calculated at such a date is still returned with the error,
+// but is an extrapolation and should be used with caution.
+type DateError struct {
+	Date  time.Time // The requested date
+	Start time.Time // The beginning of the validity period
+	End   time.Time // The end of the validity period
+	Model string    // The name of the model, e.g. WMM-2020
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("requested date %v is outside of validity period %v to %v of %s coefficients",
+		e.Date, e.Start, e.End, e.Model)
+}
+
+// Is reports whether the target is ErrDateOutOfRange.
+func (e *DateError) Is(target error) bool {
+	return target==ErrDateOutOfRange
+}
+
+// HeightError reports a requested height outside of the range over which
+// a model is valid.
+//
+// The field calculated at such a height is still returned with the error,
+// but should be used with caution.
+type HeightError struct {
+	Height float64 // The requested height above the WGS84 ellipsoid, in meters
+	Min    float64 // The minimum valid height, in meters
+	Max    float64 // The maximum valid height, in meters
+}
+
+func (e *HeightError) Error() string {
+	return fmt.Sprintf("requested height %.0fm is outside of valid range %.0fm to %.0fm",
+		e.Height, e.Min, e.Max)
+}
+
+// Is reports whether the target is ErrHeightOutOfRange.
+func (e *HeightError) Is(target error) bool {
+	return target==ErrHeightOutOfRange
+}
+
+// checkHeight returns a *HeightError if the height h in meters above the
+// WGS84 ellipsoid is outside of the valid range of the WMM.
+func checkHeight(h float64) (err error) {
+	if h<MinHeight || h>MaxHeight {
+		return &HeightError{Height: h, Min: MinHeight, Max: MaxHeight}
+	}
+	return nil
+}
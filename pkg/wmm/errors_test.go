@@ -0,0 +1,85 @@
+package wmm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestHeightError(t *testing.T) {
+	tt := DecimalYear(2022.5).ToTime()
+	for _, h := range []float64{-1000, 0, 850000} {
+		if _, err := CalculateWMMMagneticField(egm96.NewLocationGeodetic(40, -105, h), tt); err != nil {
+			t.Errorf("%sunexpected error %v at height %v%s", red, err, h, reset)
+		}
+	}
+
+	for _, h := range []float64{-1001, 850001} {
+		loc := egm96.NewLocationGeodetic(40, -105, h)
+		mag, err := CalculateWMMMagneticField(loc, tt)
+		if !errors.Is(err, ErrHeightOutOfRange) || errors.Is(err, ErrDateOutOfRange) {
+			t.Errorf("%sexpected a height error at height %v, got %v%s", red, h, err, reset)
+			continue
+		}
+		var he *HeightError
+		if !errors.As(err, &he) {
+			t.Errorf("%sexpected a *HeightError, got %T%s", red, err, reset)
+			continue
+		}
+		testDiff("Height", he.Height, h, 1e-6, t)
+		testDiff("Min", he.Min, MinHeight, 0, t)
+		testDiff("Max", he.Max, MaxHeight, 0, t)
+		if mag.F()==0 {
+			t.Errorf("%sexpected the field to be returned with the error%s", red, reset)
+		}
+	}
+}
+
+func TestDateError(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := egm96.NewLocationGeodetic(40, -105, 0)
+
+	for _, tt := range []time.Time{
+		time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		// A bad height as well should still report the date
+		for _, h := range []float64{0, 900000} {
+			loc = egm96.NewLocationGeodetic(40, -105, h)
+			mag, err := w.MagneticField(loc, tt)
+			if !errors.Is(err, ErrDateOutOfRange) {
+				t.Errorf("%sexpected a date error for %v, got %v%s", red, tt, err, reset)
+				continue
+			}
+			var de *DateError
+			if !errors.As(err, &de) {
+				t.Errorf("%sexpected a *DateError, got %T%s", red, err, reset)
+				continue
+			}
+			if !de.Date.Equal(tt) || !de.Start.Equal(w.ValidDate) ||
+				!de.End.Equal(DecimalYear(2025).ToTime()) || de.Model!="WMM-2020" {
+				t.Errorf("%sbad DateError %+v%s", red, de, reset)
+			}
+			if mag.F()==0 {
+				t.Errorf("%sexpected the field to be returned with the error%s", red, reset)
+			}
+		}
+	}
+
+	if _, _, _, _, err = w.Coefficients(1, 0, DecimalYear(2026).ToTime()); !errors.Is(err, ErrDateOutOfRange) {
+		t.Errorf("%sexpected a date error from Coefficients, got %v%s", red, err, reset)
+	}
+	if _, err = ModelForTime(DecimalYear(2030).ToTime()); !errors.Is(err, ErrDateOutOfRange) {
+		t.Errorf("%sexpected a date error from ModelForTime, got %v%s", red, err, reset)
+	}
+	_, err = w.MagneticFieldGrid([]float64{0}, []float64{0}, []float64{0, -2000},
+		[]time.Time{DecimalYear(2022).ToTime()})
+	if !errors.Is(err, ErrHeightOutOfRange) {
+		t.Errorf("%sexpected a height error from MagneticFieldGrid, got %v%s", red, err, reset)
+	}
+}
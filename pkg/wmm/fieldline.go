@@ -0,0 +1,296 @@
+package wmm
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// Step control of the field line tracer
+const (
+	traceTol     = 1     // Largest position error allowed per step, m
+	traceMaxStep = 100e3 // Largest step along the field line, m
+	traceMaxLen  = 1e9   // Longest field line traced before giving up, m
+)
+
+// FieldLine is a magnetic field line traced from a starting location to its
+// footpoint in the opposite hemisphere.
+type FieldLine struct {
+	Path      []egm96.Location // Locations along the field line from the start to the footpoint
+	Footpoint egm96.Location   // The magnetic conjugate point at the chosen height
+	Apex      egm96.Location   // The location on the field line furthest from the center of the Earth
+	L         float64          // The McIlwain L-shell parameter, in Earth radii
+}
+
+// TraceFieldLine traces the field line of the default Model through the
+// input location at the input time.
+// See Model.TraceFieldLine.
+func TraceFieldLine(loc egm96.Location, t time.Time, height float64) (line FieldLine, err error) {
+	return DefaultModel().TraceFieldLine(loc, t, height)
+}
+
+// TraceFieldLine traces the field line of the Model through the input
+// location at the input time, away from the Earth, until it returns to the
+// input height in meters above the WGS84 ellipsoid, e.g. 110km for auroral
+// studies, in the opposite hemisphere.
+//
+// The field line is integrated in Earth-centered Cartesian coordinates with
+// an adaptive Runge-Kutta 4(5) (Dormand-Prince) method, taking steps of at
+// most 100km with a position error of at most 1m per step.
+//
+// The McIlwain L value is that of a particle mirroring at the input location,
+// calculated from the integral invariant along the field line by Hilton's
+// approximation, with the magnetic moment of the centered dipole.
+// It is in units of the geomagnetic reference radius AGeo.
+//
+// Only the internal field of the Model is traced, which is not realistic
+// far from the Earth; the WMM height limits are not applied along the line.
+// As for MagneticField, an informational *DateError is returned if the
+// requested time is outside the validity period of the Model.
+// An error is returned if the field line does not return to the input height.
+func (w *Model) TraceFieldLine(loc egm96.Location, t time.Time, height float64) (line FieldLine, err error) {
+	dateErr := w.checkDate(t)
+	d, _ := w.Dipole(t)
+
+	// Follow the field upwards, away from the Earth
+	m, _ := w.MagneticField(loc, t)
+	_, _, z, _, _, _ := m.Spherical()
+	sgn := 1.0
+	if z>0 {
+		sgn = -1
+	}
+	dir := func(p [3]float64) (v [3]float64, b float64) {
+		v, b = w.fieldECEF(p, t)
+		for i := range v {
+			v[i] *= sgn/b
+		}
+		return v, b
+	}
+
+	p := locationToECEF(loc)
+	v, b := dir(p)
+	bm := b
+	line.Path = append(line.Path, loc)
+	apexR, apexP := norm(p), p
+	var (
+		s, integral float64
+		foot        bool
+		q, qv       [3]float64 // The point before p and the direction there
+		qh          float64    // The step from q to p, zero at the start
+	)
+	h := 1000.0
+	for s<traceMaxLen {
+		pNew, errEst := rk45Step(dir, p, v, h)
+		if errEst>traceTol && h>1 {
+			h *= math.Max(0.2, 0.9*math.Pow(traceTol/errEst, 0.2))
+			continue
+		}
+		vNew, bNew := dir(pNew)
+		rNew := norm(pNew)
+		if rNew>apexR {
+			apexR, apexP = rNew, pNew
+		} else if apexP==p && qh>0 {
+			// The apex lies between q and pNew, so search for it from q
+			x := goldenMax(func(x float64) float64 {
+				pa, _ := rk45Step(dir, q, qv, x)
+				return norm(pa)
+			}, qh+h)
+			apexP, _ = rk45Step(dir, q, qv, x)
+		}
+
+		if !foot && rNew<norm(p) && heightOf(pNew)<height {
+			// Bisect the step for the footpoint
+			lo, hi := 0.0, h
+			for i:=0; i<40; i++ {
+				mid := (lo+hi)/2
+				if pm, _ := rk45Step(dir, p, v, mid); heightOf(pm)<height {
+					hi = mid
+				} else {
+					lo = mid
+				}
+			}
+			pf, _ := rk45Step(dir, p, v, (lo+hi)/2)
+			line.Footpoint = ecefToLocation(pf)
+			line.Path = append(line.Path, line.Footpoint)
+			foot = true
+		} else if !foot {
+			line.Path = append(line.Path, ecefToLocation(pNew))
+		}
+
+		// The integral invariant accumulates where the field is weaker than at the mirror point.
+		// Past the footpoint, continue only to the conjugate mirror point, and not deep into the Earth.
+		integral += invariantSegment(1-b/bm, 1-bNew/bm, h)
+		if foot && (bNew>=bm || rNew<AGeo/2) {
+			break
+		}
+
+		q, qv, qh = p, v, h
+		p, v, b, s = pNew, vNew, bNew, s+h
+		h = math.Min(traceMaxStep, h*math.Min(5, 0.9*math.Pow(traceTol/math.Max(errEst, 1e-9), 0.2)))
+	}
+	if !foot {
+		return line, fmt.Errorf("field line did not return to height %.0fm within %.0fkm",
+			height, traceMaxLen/1000)
+	}
+
+	line.Apex = ecefToLocation(apexP)
+	line.L = mcIlwainL(integral/AGeo, bm/d.B0)
+	return line, dateErr
+}
+
+// goldenMax returns the x between 0 and span which maximizes f(x),
+// by golden section search.
+func goldenMax(f func(float64) float64, span float64) (x float64) {
+	const ratio = 0.6180339887498949
+	lo, hi := 0.0, span
+	x1, x2 := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+	f1, f2 := f(x1), f(x2)
+	for i:=0; i<60; i++ {
+		if f1<f2 {
+			lo, x1, f1 = x1, x2, f2
+			x2 = lo+ratio*(hi-lo)
+			f2 = f(x2)
+		} else {
+			hi, x2, f2 = x2, x1, f1
+			x1 = hi-ratio*(hi-lo)
+			f1 = f(x1)
+		}
+	}
+	return (lo+hi)/2
+}
+
+// invariantSegment returns the integral of sqrt(u) over a segment of length
+// ds over which u varies linearly from u0 to u1, counting only where u>0.
+func invariantSegment(u0, u1, ds float64) (i float64) {
+	if u0<=0 && u1<=0 {
+		return 0
+	}
+	if u0<0 {
+		ds *= u1/(u1-u0)
+		u0 = 0
+	} else if u1<0 {
+		ds *= u0/(u0-u1)
+		u1 = 0
+	}
+	if math.Abs(u1-u0)<1e-12 {
+		return math.Sqrt(u0)*ds
+	}
+	return 2.0/3*(math.Pow(u1, 1.5)-math.Pow(u0, 1.5))/(u1-u0)*ds
+}
+
+// mcIlwainL returns the McIlwain L value by Hilton's approximation, given
+// the integral invariant i in Earth radii and the ratio of the mirror
+// field to the dipole field at the reference radius.
+func mcIlwainL(i, bmOverB0 float64) (l float64) {
+	x := i*i*i*bmOverB0
+	f := 1 + 1.35047*math.Cbrt(x) + 0.465376*math.Pow(x, 2.0/3) + 0.047595*x
+	return math.Cbrt(f/bmOverB0)
+}
+
+// fieldECEF returns the unit vector along the magnetic field at the
+// Earth-centered Cartesian location p, in meters, and the field strength.
+//
+// The field is summed directly rather than through MagneticField, as every
+// step of the trace is at a new location, which would only replace the
+// cached field of the Model.
+func (w *Model) fieldECEF(p [3]float64, t time.Time) (b [3]float64, f float64) {
+	phi, lambda, r := ecefToSpherical(p)
+	pnm, dp := legendreTerms(w.nMax, phi)
+	cosML, sinML := longitudeTerms(w.nMax, lambda)
+	dt := float64(TimeToDecimalYears(t) - TimeToDecimalYears(w.ValidDate))
+	m := w.sumField(pnm, dp, radialTerms(w.nMax, r), cosML, sinML, phi, r).extrapolated(dt)
+	x, y, z, _, _, _ := m.Spherical()
+	axes := nedAxes(phi, lambda)
+	for i := range b {
+		b[i] = x*axes[0][i] + y*axes[1][i] + z*axes[2][i]
+	}
+	return b, m.F()
+}
+
+// rk45Step takes a step of length h along the direction field dir from p,
+// where the direction is v, by the Dormand-Prince Runge-Kutta 4(5) method.
+// It returns the fifth order estimate of the new position and the magnitude
+// of the difference from the fourth order estimate.
+func rk45Step(dir func([3]float64) ([3]float64, float64), p, v [3]float64, h float64) (pNew [3]float64, errEst float64) {
+	a := [6][6]float64{
+		{1.0/5},
+		{3.0/40, 9.0/40},
+		{44.0/45, -56.0/15, 32.0/9},
+		{19372.0/6561, -25360.0/2187, 64448.0/6561, -212.0/729},
+		{9017.0/3168, -355.0/33, 46732.0/5247, 49.0/176, -5103.0/18656},
+		{35.0/384, 0, 500.0/1113, 125.0/192, -2187.0/6784, 11.0/84},
+	}
+	// Differences between the fifth and fourth order weights
+	e := [7]float64{71.0/57600, 0, -71.0/16695, 71.0/1920, -17253.0/339200, 22.0/525, -1.0/40}
+
+	var k [7][3]float64
+	k[0] = v
+	for i:=0; i<6; i++ {
+		var q [3]float64
+		for j := range q {
+			q[j] = p[j]
+			for l:=0; l<=i; l++ {
+				q[j] += h*a[i][l]*k[l][j]
+			}
+		}
+		if i==5 {
+			pNew = q
+		}
+		k[i+1], _ = dir(q)
+	}
+	var d [3]float64
+	for j := range d {
+		for l := range e {
+			d[j] += h*e[l]*k[l][j]
+		}
+	}
+	return pNew, norm(d)
+}
+
+// nedAxes returns the north, east and down unit vectors in Earth-centered
+// Cartesian coordinates at the input latitude and longitude, in radians.
+func nedAxes(phi, lambda float64) (axes [3][3]float64) {
+	sinPhi, cosPhi := math.Sincos(phi)
+	sinL, cosL := math.Sincos(lambda)
+	return [3][3]float64{
+		{-sinPhi*cosL, -sinPhi*sinL, cosPhi},
+		{-sinL, cosL, 0},
+		{-cosPhi*cosL, -cosPhi*sinL, -sinPhi},
+	}
+}
+
+// locationToECEF returns the Earth-centered Cartesian coordinates of the
+// input location, in meters.
+func locationToECEF(loc egm96.Location) (p [3]float64) {
+	phi, lambda, r := loc.Spherical()
+	u := unitVector(phi, lambda)
+	return [3]float64{r*u[0], r*u[1], r*u[2]}
+}
+
+// ecefToSpherical returns the spherical latitude and longitude, in radians,
+// and the distance from the center of the Earth of the input Earth-centered
+// Cartesian coordinates, in meters.
+func ecefToSpherical(p [3]float64) (phi, lambda, r float64) {
+	r = norm(p)
+	return math.Asin(p[2]/r), math.Atan2(p[1], p[0]), r
+}
+
+// ecefToLocation returns the location of the input Earth-centered Cartesian
+// coordinates, in meters.
+func ecefToLocation(p [3]float64) (loc egm96.Location) {
+	phi, lambda, r := ecefToSpherical(p)
+	return egm96.NewLocationSpherical(phi/egm96.Deg, lambda/egm96.Deg, r)
+}
+
+// heightOf returns the height above the WGS84 ellipsoid of the input
+// Earth-centered Cartesian coordinates, in meters.
+func heightOf(p [3]float64) (h float64) {
+	_, _, h = ecefToLocation(p).Geodetic()
+	return h
+}
+
+func norm(v [3]float64) float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
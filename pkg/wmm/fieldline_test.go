@@ -0,0 +1,81 @@
+package wmm
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestTraceFieldLineDipole(t *testing.T) {
+	// For an axial dipole, field lines are r = L cos²(latitude)
+	w, err := ReadModel(strings.NewReader("2020.0 DIPOLE 12/10/2019\n 1 0 -30000.0 0.0 0.0 0.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022).ToTime()
+	loc := egm96.NewLocationSpherical(60, 30, AGeo)
+	_, _, h := loc.Geodetic()
+
+	line, err := w.TraceFieldLine(loc, tt, h)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	phi, lambda, r := line.Footpoint.Spherical()
+	testDiff("Footpoint latitude", phi/egm96.Deg, -60, 1e-6, t)
+	testDiff("Footpoint longitude", lambda/egm96.Deg, 30, 1e-6, t)
+	testDiff("Footpoint radius", r, AGeo, 1e-3, t)
+	phi, _, r = line.Apex.Spherical()
+	testDiff("Apex latitude", phi/egm96.Deg, 0, 0.01, t)
+	testDiff("Apex radius", r/AGeo, 4, 1e-6, t)
+	testDiff("L", line.L, 4, 0.01, t)
+
+	for _, p := range line.Path {
+		phi, _, r = p.Spherical()
+		testDiff("Path", r/AGeo, 4*math.Cos(phi)*math.Cos(phi), 1e-6, t)
+	}
+}
+
+func TestTraceFieldLineConjugate(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022).ToTime()
+	loc := egm96.NewLocationGeodetic(64.8, -147.7, 110e3) // Above Fairbanks, Alaska
+
+	line, err := w.TraceFieldLine(loc, tt, 110e3)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	lat, lng, h := line.Footpoint.Geodetic()
+	if lat/egm96.Deg> -50 || lat/egm96.Deg< -70 {
+		t.Errorf("%sexpected the conjugate point in the southern hemisphere, got %5.2f, %6.2f%s",
+			red, lat/egm96.Deg, lng/egm96.Deg, reset)
+	}
+	testDiff("Footpoint height", h, 110e3, 1e-3, t)
+	if line.L<4 || line.L>7 {
+		t.Errorf("%sexpected an L value around 5.5, got %5.2f%s", red, line.L, reset)
+	}
+	if !w.cached || !w.curLoc.Equals(loc) {
+		t.Errorf("%sexpected tracing to leave the field at the starting location cached%s", red, reset)
+	}
+	if !line.Path[len(line.Path)-1].Equals(line.Footpoint) {
+		t.Errorf("%sexpected the path to end at the footpoint%s", red, reset)
+	}
+
+	// Tracing back from the conjugate point returns to the start
+	back, err := w.TraceFieldLine(line.Footpoint, tt, 110e3)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	lat0, lng0, _ := loc.Geodetic()
+	lat, lng, h = back.Footpoint.Geodetic()
+	testDiff("Return latitude", lat/egm96.Deg, lat0/egm96.Deg, 1e-4, t)
+	testDiff("Return longitude", lng/egm96.Deg, lng0/egm96.Deg, 1e-4, t)
+	testDiff("Return height", h, 110e3, 1e-3, t)
+	_, _, hA := line.Apex.Geodetic()
+	_, _, hB := back.Apex.Geodetic()
+	testDiff("Apex height", hB, hA, 10, t)
+}
@@ -0,0 +1,44 @@
+package wmm
+
+// NED returns the magnetic field in the local North-East-Down frame, in which
+// the horizontal directions are parallel to the WGS84 ellipsoid.
+// It is the same as Ellipsoidal.
+//
+// Field strengths are in nT and field strength changes in nT/Year.
+func (m MagneticField) NED() (n, e, d, dn, de, dd float64) {
+	return m.Ellipsoidal()
+}
+
+// ENU returns the magnetic field in the local East-North-Up frame, in which
+// the horizontal directions are parallel to the WGS84 ellipsoid, as commonly
+// used in GNSS and INS work.
+//
+// Field strengths are in nT and field strength changes in nT/Year.
+func (m MagneticField) ENU() (e, n, u, de, dn, du float64) {
+	n, e, d, dn, de, dd := m.Ellipsoidal()
+	return e, n, -d, de, dn, -dd
+}
+
+// ECEF returns the magnetic field in Earth-Centered, Earth-Fixed Cartesian
+// axes, with X towards latitude 0º longitude 0º, Y towards latitude 0º
+// longitude 90º E, and Z towards the north pole.
+//
+// The ECEF field is the same whichever local frame it is calculated from;
+// here it is rotated from the NED frame at the geodetic latitude and longitude.
+//
+// Field strengths are in nT and field strength changes in nT/Year.
+func (m MagneticField) ECEF() (x, y, z, dx, dy, dz float64) {
+	lat, lng, _ := m.l.Geodetic()
+	axes := nedAxes(lat, lng)
+	n, e, d, dn, de, dd := m.Ellipsoidal()
+	v := [3]float64{n, e, d}
+	dv := [3]float64{dn, de, dd}
+	var b, db [3]float64
+	for i := range b {
+		for j := range v {
+			b[i] += v[j]*axes[j][i]
+			db[i] += dv[j]*axes[j][i]
+		}
+	}
+	return b[0], b[1], b[2], db[0], db[1], db[2]
+}
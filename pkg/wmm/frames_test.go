@@ -0,0 +1,62 @@
+package wmm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestFrames(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022.5).ToTime()
+
+	for _, loc := range []egm96.Location{
+		egm96.NewLocationGeodetic(0, 0, 0),
+		egm96.NewLocationGeodetic(47.6, -122.3, 100),
+		egm96.NewLocationGeodetic(-33.9, 151.2, 0),
+		egm96.NewLocationGeodetic(80, 10, 400000),
+	} {
+		m, _ := w.MagneticField(loc, tt)
+		x, y, z, dx, dy, dz := m.Ellipsoidal()
+
+		n, e, d, dn, de, dd := m.NED()
+		testDiff("NED N", n, x, 1e-9, t)
+		testDiff("NED E", e, y, 1e-9, t)
+		testDiff("NED D", d, z, 1e-9, t)
+		testDiff("NED dD", dd, dz, 1e-9, t)
+
+		e, n, u, de, dn, du := m.ENU()
+		testDiff("ENU E", e, y, 1e-9, t)
+		testDiff("ENU N", n, x, 1e-9, t)
+		testDiff("ENU U", u, -z, 1e-9, t)
+		testDiff("ENU dE", de, dy, 1e-9, t)
+		testDiff("ENU dN", dn, dx, 1e-9, t)
+		testDiff("ENU dU", du, -dz, 1e-9, t)
+
+		// The ECEF field from the ellipsoidal frame matches that from the spherical frame
+		ex, ey, ez, edx, edy, edz := m.ECEF()
+		sx, sy, sz, sdx, sdy, sdz := m.Spherical()
+		phi, lambda, _ := loc.Spherical()
+		axes := nedAxes(phi, lambda)
+		for i, v := range [3]float64{ex, ey, ez} {
+			testDiff("ECEF", v, sx*axes[0][i]+sy*axes[1][i]+sz*axes[2][i], 1e-6, t)
+		}
+		for i, v := range [3]float64{edx, edy, edz} {
+			testDiff("ECEF rate", v, sdx*axes[0][i]+sdy*axes[1][i]+sdz*axes[2][i], 1e-9, t)
+		}
+		testDiff("ECEF F", math.Sqrt(ex*ex+ey*ey+ez*ez), m.F(), 1e-6, t)
+		testDiff("ECEF DF", (ex*edx+ey*edy+ez*edz)/m.F(), m.DF(), 1e-9, t)
+	}
+
+	// At 0º, 0º, north is +Z, east is +Y and down is -X
+	m, _ := w.MagneticField(egm96.NewLocationGeodetic(0, 0, 0), tt)
+	n, e, d, _, _, _ := m.NED()
+	x, y, z, _, _, _ := m.ECEF()
+	testDiff("ECEF X at 0,0", x, -d, 1e-6, t)
+	testDiff("ECEF Y at 0,0", y, e, 1e-6, t)
+	testDiff("ECEF Z at 0,0", z, n, 1e-6, t)
+}
@@ -0,0 +1,152 @@
+package wmm
+
+import (
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// Dipole represents the centered dipole of the main field at a given time,
+// given by the degree 1 coefficients g10, g11 and h11, which defines the
+// geomagnetic coordinate system.
+//
+// The geomagnetic north pole is where the dipole axis meets the Earth's
+// surface in the northern hemisphere, and the geomagnetic south pole is
+// antipodal to it.  These differ from the dip poles, where the actual field
+// is vertical.
+// Geomagnetic latitude is measured from the geomagnetic equator and
+// geomagnetic longitude from the meridian through the geographic south pole.
+type Dipole struct {
+	Time     time.Time // The time at which the dipole is calculated
+	B0       float64   // The dipole field strength at the reference radius on the equator, nT
+	NorthLat float64   // The spherical latitude of the geomagnetic north pole, º
+	NorthLng float64   // The longitude of the geomagnetic north pole, º
+	rot      [3][3]float64 // Rotation from geographic to geomagnetic Cartesian axes
+}
+
+// GetWMMDipole returns the centered dipole of the default Model at the input time.
+// See Model.Dipole.
+func GetWMMDipole(t time.Time) (d Dipole, err error) {
+	return DefaultModel().Dipole(t)
+}
+
+// Dipole returns the centered dipole of the Model at the input time.
+//
+// As for MagneticField, an informational *DateError is returned if the
+// requested time is outside the validity period of the Model, but the
+// dipole is still calculated.
+func (w *Model) Dipole(t time.Time) (d Dipole, err error) {
+	g10, _, _, _, err := w.Coefficients(1, 0, t)
+	g11, h11, _, _, _ := w.Coefficients(1, 1, t)
+	return newDipole(g10, g11, h11, t), err
+}
+
+func newDipole(g10, g11, h11 float64, t time.Time) (d Dipole) {
+	d.Time = t
+	d.B0 = math.Sqrt(g10*g10 + g11*g11 + h11*h11)
+	theta := math.Acos(-g10/d.B0)
+	lambda := math.Atan2(-h11, -g11)
+	d.NorthLat = 90 - theta/egm96.Deg
+	d.NorthLng = lambda/egm96.Deg
+
+	sinT, cosT := math.Sincos(theta)
+	sinL, cosL := math.Sincos(lambda)
+	d.rot = [3][3]float64{
+		{cosT*cosL, cosT*sinL, -sinT},
+		{-sinL, cosL, 0},
+		{sinT*cosL, sinT*sinL, cosT},
+	}
+	return d
+}
+
+// Poles returns the locations on the WGS84 ellipsoid of the geomagnetic
+// north and south poles.
+func (d Dipole) Poles() (north, south egm96.Location) {
+	return surfaceLocation(d.NorthLat, d.NorthLng), surfaceLocation(-d.NorthLat, d.NorthLng+180)
+}
+
+// surfaceLocation returns the location on the WGS84 ellipsoid at the input
+// spherical latitude and longitude, in degrees.
+func surfaceLocation(lat, lng float64) (loc egm96.Location) {
+	cosPhi := math.Cos(lat*egm96.Deg)
+	r := egm96.A*math.Sqrt(1-egm96.E2)/math.Sqrt(1-egm96.E2*cosPhi*cosPhi)
+	return egm96.NewLocationSpherical(lat, math.Mod(lng+540, 360)-180, r)
+}
+
+// Geomagnetic returns the geomagnetic latitude and longitude of the input
+// location, in degrees.  The longitude is between -180º and 180º.
+//
+// The distance from the center of the Earth is unchanged by the transformation
+// and is given by loc.Spherical.
+func (d Dipole) Geomagnetic(loc egm96.Location) (lat, lng float64) {
+	phi, lambda, _ := loc.Spherical()
+	return d.toGeomagnetic(phi, lambda)
+}
+
+// toGeomagnetic rotates the spherical latitude and longitude, in radians,
+// to geomagnetic latitude and longitude in degrees.
+func (d Dipole) toGeomagnetic(phi, lambda float64) (lat, lng float64) {
+	u := unitVector(phi, lambda)
+	var v [3]float64
+	for i := range v {
+		for j := range u {
+			v[i] += d.rot[i][j]*u[j]
+		}
+	}
+	return math.Asin(math.Max(-1, math.Min(1, v[2])))/egm96.Deg, math.Atan2(v[1], v[0])/egm96.Deg
+}
+
+// Location returns the location with the input geomagnetic latitude and
+// longitude, in degrees, at the distance r in meters from the center of the
+// Earth.  It is the inverse of Geomagnetic.
+func (d Dipole) Location(lat, lng, r float64) (loc egm96.Location) {
+	v := unitVector(lat*egm96.Deg, lng*egm96.Deg)
+	var u [3]float64
+	for i := range u {
+		for j := range v {
+			u[i] += d.rot[j][i]*v[j]
+		}
+	}
+	return egm96.NewLocationSpherical(math.Asin(math.Max(-1, math.Min(1, u[2])))/egm96.Deg,
+		math.Atan2(u[1], u[0])/egm96.Deg, r)
+}
+
+// MagneticLocalTime returns the magnetic local time in hours, from 0 to 24,
+// at the input location at the input UTC time.
+//
+// Magnetic local time is 12 at the geomagnetic longitude of the subsolar
+// point and increases by one hour for every 15º of geomagnetic longitude
+// eastwards of it.
+func (d Dipole) MagneticLocalTime(loc egm96.Location, t time.Time) (mlt float64) {
+	_, lng := d.Geomagnetic(loc)
+	_, lngS := d.toGeomagnetic(subsolarPoint(t))
+	return math.Mod(12+(lng-lngS)/15+48, 24)
+}
+
+// unitVector returns the Cartesian unit vector at the input latitude and
+// longitude, in radians.
+func unitVector(phi, lambda float64) (u [3]float64) {
+	sinPhi, cosPhi := math.Sincos(phi)
+	sinL, cosL := math.Sincos(lambda)
+	return [3]float64{cosPhi*cosL, cosPhi*sinL, sinPhi}
+}
+
+// subsolarPoint returns the latitude and longitude, in radians, of the point
+// at which the Sun is overhead at the input time.
+//
+// It uses the low-precision solar coordinates of the Astronomical Almanac,
+// which are accurate to about 0.01º between 1950 and 2050.
+func subsolarPoint(t time.Time) (phi, lambda float64) {
+	n := float64(t.Sub(time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)))/float64(24*time.Hour)
+	// Mean longitude and mean anomaly of the Sun, ecliptic longitude and obliquity
+	l := (280.460 + 0.9856474*n)*egm96.Deg
+	g := (357.528 + 0.9856003*n)*egm96.Deg
+	ecl := l + (1.915*math.Sin(g) + 0.020*math.Sin(2*g))*egm96.Deg
+	obl := (23.439 - 0.0000004*n)*egm96.Deg
+	ra := math.Atan2(math.Cos(obl)*math.Sin(ecl), math.Cos(ecl))
+	gmst := (280.46061837 + 360.98564736629*n)*egm96.Deg
+	phi = math.Asin(math.Sin(obl)*math.Sin(ecl))
+	lambda = math.Remainder(ra-gmst, 2*math.Pi)
+	return phi, lambda
+}
@@ -0,0 +1,94 @@
+package wmm
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestDipolePoles(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WMM2020 report: the geomagnetic north pole at 2020.0 is at 80.65ºN, 72.68ºW,
+	// or 80.59ºN in spherical latitude
+	d, err := w.Dipole(DecimalYear(2020).ToTime())
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	testDiff("North pole latitude", d.NorthLat, 80.59, 0.01, t)
+	testDiff("North pole longitude", d.NorthLng, -72.68, 0.01, t)
+	testDiff("B0", d.B0, 29806, 1, t)
+
+	// The published pole positions are geodetic
+	north, south := d.Poles()
+	lat, lng, _ := north.Geodetic()
+	testDiff("North pole geodetic latitude", lat/egm96.Deg, 80.65, 0.01, t)
+	testDiff("North pole geodetic longitude", lng/egm96.Deg, -72.68, 0.01, t)
+	for _, p := range []struct {
+		name string
+		loc  egm96.Location
+		lat  float64
+	}{{"north", north, 90}, {"south", south, -90}} {
+		lat, _ := d.Geomagnetic(p.loc)
+		testDiff(p.name+" pole geomagnetic latitude", lat, p.lat, 1e-9, t)
+		_, _, h := p.loc.Geodetic()
+		testDiff(p.name+" pole height", h, 0, 1e-6, t)
+	}
+
+	// The geographic north pole is on the geomagnetic meridian 180º
+	lat, lng = d.Geomagnetic(egm96.NewLocationGeodetic(90, 0, 0))
+	testDiff("Geographic pole latitude", lat, d.NorthLat, 1e-9, t)
+	testDiff("Geographic pole longitude", math.Abs(lng), 180, 1e-9, t)
+}
+
+func TestGeomagneticRoundTrip(t *testing.T) {
+	d, err := GetWMMDipole(DecimalYear(2022.5).ToTime())
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, lat := range []float64{-89, -45, 0, 30, 60, 89} {
+		for _, lng := range []float64{-179, -90, 0, 45, 135} {
+			loc := egm96.NewLocationGeodetic(lat, lng, 10000)
+			mLat, mLng := d.Geomagnetic(loc)
+			_, _, r := loc.Spherical()
+			loc2 := d.Location(mLat, mLng, r)
+			lat2, lng2, h2 := loc2.Geodetic()
+			name := fmt.Sprintf("(%3.0f,%4.0f)", lat, lng)
+			testDiff(name+" latitude", lat2/egm96.Deg, lat, 1e-9, t)
+			testDiff(name+" longitude", lng2/egm96.Deg, lng, 1e-9, t)
+			testDiff(name+" height", h2, 10000, 1e-6, t)
+		}
+	}
+}
+
+func TestMagneticLocalTime(t *testing.T) {
+	// Near the equinox the subsolar point is near the equator, and its longitude
+	// at noon UTC is given by the equation of time, about 7.5 minutes
+	tt := time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC)
+	phi, lambda := subsolarPoint(tt)
+	testDiff("Equinox subsolar latitude", phi/egm96.Deg, 0, 0.2, t)
+	testDiff("Equinox subsolar longitude", lambda/egm96.Deg, 1.85, 0.1, t)
+	phiS, _ := subsolarPoint(time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC))
+	testDiff("Solstice subsolar latitude", phiS/egm96.Deg, 23.44, 0.01, t)
+
+	d, _ := GetWMMDipole(tt)
+	loc := egm96.NewLocationSpherical(phi/egm96.Deg, lambda/egm96.Deg, 7e6)
+	testDiff("Subsolar MLT", d.MagneticLocalTime(loc, tt), 12, 1e-9, t)
+
+	// Magnetic local time advances by about an hour for every hour of UTC,
+	// but not uniformly as the dipole axis is tilted
+	loc = egm96.NewLocationGeodetic(40, -105, 0)
+	mlt := d.MagneticLocalTime(loc, tt)
+	mlt2 := d.MagneticLocalTime(loc, tt.Add(6*time.Hour))
+	testDiff("MLT after 6h", math.Mod(mlt2-mlt+24, 24), 6, 0.1, t)
+
+	// Opposite geomagnetic longitudes differ by 12 hours
+	mLat, mLng := d.Geomagnetic(loc)
+	opp := d.Location(mLat, mLng+180, 7e6)
+	testDiff("Opposite MLT", math.Mod(d.MagneticLocalTime(opp, tt)-mlt+24, 24), 12, 1e-9, t)
+}
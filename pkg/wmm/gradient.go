@@ -0,0 +1,78 @@
+package wmm
+
+import "math"
+
+// Gradient returns the spatial gradient tensor of the magnetic field in
+// ellipsoidal coordinate axes, in nT/km.
+//
+// g[i][j] is the derivative of the component i of the field, X, Y or Z as
+// returned by Ellipsoidal, with respect to displacement in the direction j,
+// north, east or down, parallel to the WGS84 ellipsoid.
+// The components are taken along fixed axes at the location of the field,
+// so that the tensor is symmetric and, as the field is free of sources
+// outside the Earth, has zero trace.
+//
+// The gradient is calculated analytically from the same spherical harmonic
+// expansion as the field.  Like the Y component, it is undefined exactly at
+// the geographic poles.
+func (m MagneticField) Gradient() (g [3][3]float64) {
+	latS, _, _ := m.l.Spherical()
+	latG, _, _ := m.l.Geodetic()
+	return rotateGradient(m.SphericalGradient(), latS-latG)
+}
+
+// SphericalGradient returns the spatial gradient tensor of the magnetic field
+// in spherical coordinate axes, in nT/km.
+//
+// g[i][j] is the derivative of the component i of the field, X, Y or Z as
+// returned by Spherical, with respect to displacement in the direction j,
+// north, east or down relative to the center of the Earth.
+// See Gradient.
+func (m MagneticField) SphericalGradient() (g [3][3]float64) {
+	return [3][3]float64{
+		{m.g[0], m.g[3], m.g[4]},
+		{m.g[3], m.g[1], m.g[5]},
+		{m.g[4], m.g[5], m.g[2]},
+	}
+}
+
+// rotateGradient rotates the spherical gradient tensor gs into axes rotated
+// by the angle dPhi, the spherical minus geodetic latitude, about the east axis.
+func rotateGradient(gs [3][3]float64, dPhi float64) (g [3][3]float64) {
+	sinDPhi, cosDPhi := math.Sincos(dPhi)
+	rot := [3][3]float64{
+		{cosDPhi, 0, -sinDPhi},
+		{0, 1, 0},
+		{sinDPhi, 0, cosDPhi},
+	}
+	for i:=0; i<3; i++ {
+		for j:=0; j<3; j++ {
+			for k:=0; k<3; k++ {
+				for l:=0; l<3; l++ {
+					g[i][j] += rot[i][k]*gs[k][l]*rot[j][l]
+				}
+			}
+		}
+	}
+	return g
+}
+
+// addGradient adds the term of degree n and order m of the expansion of the
+// spherical gradient tensor, NN, EE, DD, NE, ND, ED, to g, given the
+// coefficients combined with the longitude terms gc and gs, the radial
+// term f and the Legendre function p and its derivative dp.
+// The sum must be divided by the radius.
+//
+// The second derivative of the Legendre function with respect to the
+// latitude follows from Legendre's equation.
+func addGradient(g *[6]float64, n, m int, gc, gs, f, p, dp, cosPhi, tanPhi float64) {
+	nn := float64(n+1)
+	m2 := float64(m*m)/(cosPhi*cosPhi)
+	mf := float64(m)
+	g[0] += -f*gc*(tanPhi*dp + (m2-nn*nn)*p)
+	g[1] += f*gc*(tanPhi*dp + (m2+nn)*p)
+	g[2] += -nn*(nn+1)*f*gc*p
+	g[3] += f/cosPhi*mf*gs*(dp + tanPhi*p)
+	g[4] += -(nn+1)*f*gc*dp
+	g[5] += f/cosPhi*(nn+1)*mf*gs*p
+}
@@ -0,0 +1,114 @@
+package wmm
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// toECEF returns the earth-centered, earth-fixed coordinates in meters of a
+// geodetic latitude and longitude in radians and height in meters.
+func toECEF(lat, lng, h float64) (p [3]float64) {
+	sinPhi, cosPhi := math.Sincos(lat)
+	rc := egm96.A/math.Sqrt(1-egm96.E2*sinPhi*sinPhi)
+	return [3]float64{
+		(rc+h)*cosPhi*math.Cos(lng),
+		(rc+h)*cosPhi*math.Sin(lng),
+		(rc*(1-egm96.E2)+h)*sinPhi,
+	}
+}
+
+// fromECEF returns the geodetic latitude and longitude in degrees and height
+// in meters of earth-centered, earth-fixed coordinates in meters.
+func fromECEF(p [3]float64) (lat, lng, h float64) {
+	lng = math.Atan2(p[1], p[0])
+	rp := math.Hypot(p[0], p[1])
+	lat = math.Atan2(p[2], rp*(1-egm96.E2))
+	for i:=0; i<10; i++ {
+		sinPhi := math.Sin(lat)
+		rc := egm96.A/math.Sqrt(1-egm96.E2*sinPhi*sinPhi)
+		h = rp/math.Cos(lat) - rc
+		lat = math.Atan2(p[2], rp*(1-egm96.E2*rc/(rc+h)))
+	}
+	return lat/egm96.Deg, lng/egm96.Deg, h
+}
+
+func TestGradientAgainstFiniteDifferences(t *testing.T) {
+	const dh = 100 // Finite difference step, m
+
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2023.3).ToTime()
+
+	locs := []egm96.Location{
+		egm96.NewLocationGeodetic(80, 0, 0),
+		egm96.NewLocationGeodetic(-12.25, 82.75, 10000),
+		egm96.NewLocationGeodetic(45, -120, 100e3),
+		egm96.NewLocationGeodetic(-80, 240, 100e3),
+		egm96.NewLocationGeodetic(86, 160, 0),
+	}
+	for _, loc := range locs {
+		lat0, lng0, h0 := loc.Geodetic()
+		name := fmt.Sprintf("(%4.1f,%5.1f,%6.0f)", lat0/egm96.Deg, lng0/egm96.Deg, h0)
+		p0 := toECEF(lat0, lng0, h0)
+		axes0 := nedAxes(lat0, lng0)
+
+		mag, _ := w.MagneticField(loc, tt)
+		g := mag.Gradient()
+
+		// The field at a displaced location, in the axes at loc
+		fieldAt := func(j int, s float64) (b [3]float64) {
+			var p [3]float64
+			for k := range p {
+				p[k] = p0[k] + s*dh*axes0[j][k]
+			}
+			lat, lng, h := fromECEF(p)
+			m, _ := w.MagneticField(egm96.NewLocationGeodetic(lat, lng, h), tt)
+			x, y, z, _, _, _ := m.Ellipsoidal()
+			axes := nedAxes(lat*egm96.Deg, lng*egm96.Deg)
+			for i := range b {
+				for k := range p {
+					b[i] += axes0[i][k]*(x*axes[0][k] + y*axes[1][k] + z*axes[2][k])
+				}
+			}
+			return b
+		}
+
+		for j:=0; j<3; j++ {
+			bp, bm := fieldAt(j, 1), fieldAt(j, -1)
+			for i:=0; i<3; i++ {
+				fd := (bp[i]-bm[i])/(2*dh)*1000
+				testDiff(fmt.Sprintf("%s G[%d][%d]", name, i, j), g[i][j], fd, 1e-4, t)
+			}
+		}
+		testDiff(name+" trace", g[0][0]+g[1][1]+g[2][2], 0, 1e-9, t)
+	}
+}
+
+func TestGradientFromGrid(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lats, lngs, heights, times := gridAxes(5, 6, 2, 3)
+	grid, _ := w.MagneticFieldGrid(lats, lngs, heights, times)
+	for i, lat := range lats {
+		for j, lng := range lngs {
+			for k, height := range heights {
+				for l, tt := range times {
+					mag, _ := w.MagneticField(egm96.NewLocationGeodetic(lat, lng, height), tt)
+					g, gG := mag.Gradient(), grid.At(i, j, k, l).Gradient()
+					for m:=0; m<3; m++ {
+						for n:=0; n<3; n++ {
+							testDiff(fmt.Sprintf("G[%d][%d]", m, n), gG[m][n], g[m][n], 1e-9, t)
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package wmm
+
+import (
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// FieldGrid holds the magnetic field calculated over a grid of
+// latitudes, longitudes, heights and times.
+type FieldGrid struct {
+	Latitudes  []float64   // Geodetic latitudes in degrees
+	Longitudes []float64   // Longitudes in degrees
+	Heights    []float64   // Heights above the WGS84 ellipsoid in meters
+	Times      []time.Time // Times at which the field was calculated
+	fields     []MagneticField
+}
+
+// At returns the magnetic field at the grid point with the given indices
+// into Latitudes, Longitudes, Heights and Times.
+func (g FieldGrid) At(iLat, iLng, iHeight, iTime int) (field MagneticField) {
+	nLng, nHeight, nTime := len(g.Longitudes), len(g.Heights), len(g.Times)
+	return g.fields[((iLat*nHeight+iHeight)*nLng+iLng)*nTime+iTime]
+}
+
+// CalculateWMMMagneticFieldGrid returns the magnetic field at every combination
+// of the input latitudes, longitudes, heights and times.
+//
+// Latitudes and longitudes are geodetic and in degrees, heights are in meters
+// above the WGS84 ellipsoid.
+//
+// The result is identical to calling CalculateWMMMagneticField at each grid
+// point, but intermediate results are reused across the grid:
+// the Legendre functions and radial powers are calculated once for each
+// latitude and height (which together determine the spherical latitude and radius),
+// the sin(mλ) and cos(mλ) terms once for each longitude, and the full
+// spherical harmonic sum once for each location, so that each additional time
+// costs almost nothing.
+//
+// As for CalculateWMMMagneticField, an informational *DateError is returned if
+// any requested time is outside the validity period of the coefficients,
+// or else a *HeightError if any height is outside of the valid range.
+//
+// The field is calculated with the default Model.
+func CalculateWMMMagneticFieldGrid(lats, lngs, heights []float64, times []time.Time) (grid FieldGrid, err error) {
+	return DefaultModel().MagneticFieldGrid(lats, lngs, heights, times)
+}
+
+// MagneticFieldGrid returns the magnetic field of the Model at every combination
+// of the input latitudes, longitudes, heights and times.
+//
+// It behaves as CalculateWMMMagneticFieldGrid, but uses the coefficients of
+// the Model rather than those of the default Model.
+func (w *Model) MagneticFieldGrid(lats, lngs, heights []float64, times []time.Time) (grid FieldGrid, err error) {
+	grid = FieldGrid{
+		Latitudes:  lats,
+		Longitudes: lngs,
+		Heights:    heights,
+		Times:      times,
+		fields:     make([]MagneticField, len(lats)*len(lngs)*len(heights)*len(times)),
+	}
+
+	dts := make([]float64, len(times))
+	for i, t := range times {
+		if e := w.checkDate(t); e != nil && err == nil {
+			err = e
+		}
+		dts[i] = float64(TimeToDecimalYears(t) - TimeToDecimalYears(w.ValidDate))
+	}
+
+	for _, h := range heights {
+		if e := checkHeight(h); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	cosMLs := make([][]float64, len(lngs))
+	sinMLs := make([][]float64, len(lngs))
+	for i, lng := range lngs {
+		cosMLs[i], sinMLs[i] = longitudeTerms(w.nMax, lng*egm96.Deg)
+	}
+
+	k := 0
+	for _, lat := range lats {
+		for _, height := range heights {
+			phi, _, r := egm96.NewLocationGeodetic(lat, 0, height).Spherical()
+			p, dp := legendreTerms(w.nMax, phi)
+			f := radialTerms(w.nMax, r)
+			for iLng, lng := range lngs {
+				field := w.sumField(p, dp, f, cosMLs[iLng], sinMLs[iLng], phi, r)
+				field.l = egm96.NewLocationGeodetic(lat, lng, height)
+				for _, dt := range dts {
+					grid.fields[k] = field.extrapolated(dt)
+					k++
+				}
+			}
+		}
+	}
+	return grid, err
+}
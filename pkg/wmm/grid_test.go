@@ -0,0 +1,88 @@
+package wmm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func gridAxes(nLat, nLng, nHeight, nTime int) (lats, lngs, heights []float64, times []time.Time) {
+	for i:=0; i<nLat; i++ {
+		lats = append(lats, -85+170*float64(i)/float64(nLat-1))
+	}
+	for i:=0; i<nLng; i++ {
+		lngs = append(lngs, -180+360*float64(i)/float64(nLng))
+	}
+	for i:=0; i<nHeight; i++ {
+		heights = append(heights, float64(i)*50e3)
+	}
+	for i:=0; i<nTime; i++ {
+		times = append(times, DecimalYear(2020+float64(i)*0.5).ToTime())
+	}
+	return lats, lngs, heights, times
+}
+
+func TestMagneticFieldGridMatchesPoints(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lats, lngs, heights, times := gridAxes(7, 9, 3, 4)
+	grid, err := w.MagneticFieldGrid(lats, lngs, heights, times)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	for i, lat := range lats {
+		for j, lng := range lngs {
+			for k, height := range heights {
+				for l, tt := range times {
+					mag, _ := w.MagneticField(egm96.NewLocationGeodetic(lat, lng, height), tt)
+					magG := grid.At(i, j, k, l)
+					x, y, z, dx, dy, dz := mag.Spherical()
+					xG, yG, zG, dxG, dyG, dzG := magG.Spherical()
+					name := fmt.Sprintf("(%3.0f,%4.0f,%6.0f,%v)", lat, lng, height, tt)
+					testDiff(name+" X", xG, x, 1e-6, t)
+					testDiff(name+" Y", yG, y, 1e-6, t)
+					testDiff(name+" Z", zG, z, 1e-6, t)
+					testDiff(name+" DX", dxG, dx, 1e-9, t)
+					testDiff(name+" DY", dyG, dy, 1e-9, t)
+					testDiff(name+" DZ", dzG, dz, 1e-9, t)
+					testDiff(name+" D", magG.D(), mag.D(), 1e-9, t)
+				}
+			}
+		}
+	}
+
+	_, err = w.MagneticFieldGrid(lats, lngs, heights, []time.Time{DecimalYear(2030).ToTime()})
+	if err == nil {
+		t.Error("expected an error for a time outside of the validity period")
+	}
+}
+
+func BenchmarkMagneticFieldPointByPoint(b *testing.B) {
+	w, _ := LoadModel("testdata/WMM2020.COF")
+	lats, lngs, heights, times := gridAxes(19, 36, 3, 4)
+	for i:=0; i<b.N; i++ {
+		for _, lng := range lngs {
+			for _, lat := range lats {
+				for _, height := range heights {
+					loc := egm96.NewLocationGeodetic(lat, lng, height)
+					for _, tt := range times {
+						_, _ = w.MagneticField(loc, tt)
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkMagneticFieldGrid(b *testing.B) {
+	w, _ := LoadModel("testdata/WMM2020.COF")
+	lats, lngs, heights, times := gridAxes(19, 36, 3, 4)
+	for i:=0; i<b.N; i++ {
+		_, _ = w.MagneticFieldGrid(lats, lngs, heights, times)
+	}
+}
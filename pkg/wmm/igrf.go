@@ -0,0 +1,243 @@
+package wmm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// IGRFModel represents the International Geomagnetic Reference Field (IGRF),
+// a series of main field models at 5-year epochs, from 1900 to the present,
+// published by the International Association of Geomagnetism and Aeronomy (IAGA).
+//
+// Between epochs the coefficients are interpolated linearly, and after the
+// last epoch they are extrapolated with the published secular variation for
+// up to five years.
+//
+// The IGRF homepage is at https://www.ngdc.noaa.gov/IAGA/vmod/igrf.html.
+//
+// An IGRFModel is safe for concurrent use by multiple goroutines.
+type IGRFModel struct {
+	Epochs []DecimalYear // The epochs of the coefficient columns, e.g. 1900.0, 1905.0, ...
+	nMax   int
+	gnm    [][][]float64 // G(n,m) by epoch, degree and order
+	hnm    [][][]float64 // H(n,m) by epoch, degree and order
+	cDGnm  [][]float64   // Secular variation of G(n,m) after the last epoch
+	cDHnm  [][]float64   // Secular variation of H(n,m) after the last epoch
+}
+
+// LoadIGRF returns a new IGRFModel loaded from the specified coefficients file,
+// in the format of the table published by IAGA, e.g. igrf13coeffs.txt.
+//
+// The table has one line per coefficient, e.g.
+//  g/h n m 1900.0 1905.0 ... 2020.0 2020-25
+//  g 1 0 -31543 -31464 ... -29404.8 5.7
+// with one column per epoch followed by a final secular variation column.
+func LoadIGRF(fn string) (g *IGRFModel, err error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return parseIGRF(data, fn)
+}
+
+// ReadIGRF returns a new IGRFModel read from the coefficient table provided by r.
+// See LoadIGRF for the format.
+func ReadIGRF(r io.Reader) (g *IGRFModel, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseIGRF(data, "")
+}
+
+func parseIGRF(data []byte, fn string) (g *IGRFModel, err error) {
+	var (
+		n, m int
+		v    float64
+	)
+
+	g = new(IGRFModel)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		s := strings.Fields(scanner.Text())
+		if len(s)==0 || strings.HasPrefix(s[0], "#") || s[0]=="c/s" {
+			continue
+		}
+		if s[0]=="g/h" {
+			if len(s)<5 {
+				return nil, fmt.Errorf("bad epoch header in IGRF coefficient file %s", fn)
+			}
+			// The last column is the secular variation, e.g. 2020-25
+			for _, e := range s[3:len(s)-1] {
+				if v, err = strconv.ParseFloat(e, 64); err != nil {
+					return nil, fmt.Errorf("bad epoch %s in IGRF coefficient file %s", e, fn)
+				}
+				g.Epochs = append(g.Epochs, DecimalYear(v))
+			}
+			g.gnm = make([][][]float64, len(g.Epochs))
+			g.hnm = make([][][]float64, len(g.Epochs))
+			continue
+		}
+		if s[0]!="g" && s[0]!="h" {
+			continue
+		}
+		if len(g.Epochs)==0 {
+			return nil, fmt.Errorf("coefficients before epoch header in IGRF coefficient file %s", fn)
+		}
+		if len(s)!=len(g.Epochs)+4 {
+			return nil, fmt.Errorf("expected %d columns but found %d in IGRF coefficient file %s",
+				len(g.Epochs)+4, len(s), fn)
+		}
+		if n, err = strconv.Atoi(s[1]); err!=nil {
+			return nil, fmt.Errorf("bad n value in IGRF coefficient file %s", fn)
+		}
+		if m, err = strconv.Atoi(s[2]); err!=nil {
+			return nil, fmt.Errorf("bad m value in IGRF coefficient file %s", fn)
+		}
+		if n<1 || m<0 || m>n {
+			return nil, fmt.Errorf("bad n, m = (%d,%d) in IGRF coefficient file %s", n, m, fn)
+		}
+		for g.nMax<n {
+			g.nMax++
+			for i := range g.Epochs {
+				g.gnm[i] = growDegree(g.gnm[i], g.nMax)
+				g.hnm[i] = growDegree(g.hnm[i], g.nMax)
+			}
+			g.cDGnm = growDegree(g.cDGnm, g.nMax)
+			g.cDHnm = growDegree(g.cDHnm, g.nMax)
+		}
+		c, sv := g.gnm, g.cDGnm
+		if s[0]=="h" {
+			c, sv = g.hnm, g.cDHnm
+		}
+		for i := range g.Epochs {
+			if c[i][n][m], err = strconv.ParseFloat(s[i+3], 64); err != nil {
+				return nil, fmt.Errorf("bad %s(%d,%d) value for %v in IGRF coefficient file %s",
+					s[0], n, m, g.Epochs[i], fn)
+			}
+		}
+		if sv[n][m], err = strconv.ParseFloat(s[len(s)-1], 64); err != nil {
+			return nil, fmt.Errorf("bad secular variation %s(%d,%d) value in IGRF coefficient file %s",
+				s[0], n, m, fn)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(g.Epochs)==0 || g.nMax<1 {
+		return nil, fmt.Errorf("no coefficients found in IGRF coefficient file %s", fn)
+	}
+	return g, nil
+}
+
+// growDegree appends a row for degree n to the coefficient slice c, which
+// is created with a row for degree 0 if it is nil.
+func growDegree(c [][]float64, n int) [][]float64 {
+	if c==nil {
+		c = [][]float64{{0}}
+	}
+	for len(c)<=n {
+		c = append(c, make([]float64, len(c)+1))
+	}
+	return c
+}
+
+// MaxDegree returns the maximum degree (and order) n of the IGRF coefficients.
+func (g *IGRFModel) MaxDegree() (n int) {
+	return g.nMax
+}
+
+// checkDate returns a *DateError if the requested time is outside of the range
+// of validity of the IGRFModel, i.e. before the first epoch or more than
+// five years after the last.
+func (g *IGRFModel) checkDate(t time.Time) (err error) {
+	y := TimeToDecimalYears(t)
+	if y<g.Epochs[0] || y>g.Epochs[len(g.Epochs)-1]+5 {
+		return &DateError{Date: t, Start: g.Epochs[0].ToTime(),
+			End: (g.Epochs[len(g.Epochs)-1]+5).ToTime(), Model: "IGRF"}
+	}
+	return nil
+}
+
+// coefficientsAt returns the full coefficient set G(n,m), H(n,m) interpolated
+// to the input time, along with their rates of change dG(n,m), dH(n,m).
+func (g *IGRFModel) coefficientsAt(t time.Time) (gnm, hnm, dgnm, dhnm [][]float64) {
+	y := TimeToDecimalYears(t)
+	last := len(g.Epochs)-1
+	i := last
+	for i>0 && y<g.Epochs[i] {
+		i--
+	}
+	dt := float64(y-g.Epochs[i])
+
+	gnm = make([][]float64, g.nMax+1)
+	hnm = make([][]float64, g.nMax+1)
+	dgnm = make([][]float64, g.nMax+1)
+	dhnm = make([][]float64, g.nMax+1)
+	for n:=0; n<=g.nMax; n++ {
+		gnm[n] = make([]float64, n+1)
+		hnm[n] = make([]float64, n+1)
+		dgnm[n] = make([]float64, n+1)
+		dhnm[n] = make([]float64, n+1)
+		for m:=0; m<=n; m++ {
+			if i==last {
+				dgnm[n][m] = g.cDGnm[n][m]
+				dhnm[n][m] = g.cDHnm[n][m]
+			} else {
+				de := float64(g.Epochs[i+1]-g.Epochs[i])
+				dgnm[n][m] = (g.gnm[i+1][n][m]-g.gnm[i][n][m])/de
+				dhnm[n][m] = (g.hnm[i+1][n][m]-g.hnm[i][n][m])/de
+			}
+			gnm[n][m] = g.gnm[i][n][m] + dt*dgnm[n][m]
+			hnm[n][m] = g.hnm[i][n][m] + dt*dhnm[n][m]
+		}
+	}
+	return gnm, hnm, dgnm, dhnm
+}
+
+// Coefficients calculates the spherical harmonic coefficients G(n,m), H(n,m)
+// and their rates of change dG(n,m), dH(n,m) of the IGRF at the input time.
+//
+// If the request n,m are invalid or the requested time is outside of the range
+// of validity of the IGRF, it will return an error.
+func (g *IGRFModel) Coefficients(n, m int, t time.Time) (gnm, hnm, dgnm, dhnm float64, err error) {
+	if n<0 || n>g.nMax || m<0 || m>g.nMax {
+		return 0, 0, 0, 0, fmt.Errorf("n, m = (%d,%d) must be between 0 and %d",
+			n, m, g.nMax)
+	}
+	if m>n {
+		return 0, 0, 0, 0, fmt.Errorf("m=%d must be less than n=%d", m, n)
+	}
+	err = g.checkDate(t)
+	gs, hs, dgs, dhs := g.coefficientsAt(t)
+	return gs[n][m], hs[n][m], dgs[n][m], dhs[n][m], err
+}
+
+// MagneticField returns the magnetic field of the IGRF at the input location
+// at the input time.
+//
+// The returned MagneticField provides the same components and rates of change
+// as one calculated from the WMM.
+// The function will return an informational error if the requested time is
+// outside of the validity period of the IGRF, but will still return the
+// calculated field.
+func (g *IGRFModel) MagneticField(loc egm96.Location, t time.Time) (field MagneticField, err error) {
+	err = g.checkDate(t)
+	gnm, hnm, dgnm, dhnm := g.coefficientsAt(t)
+	phi, lambda, r := loc.Spherical()
+	p, dp := legendreTerms(g.nMax, phi)
+	cosML, sinML := longitudeTerms(g.nMax, lambda)
+	field = sumField(g.nMax, g.nMax, gnm, hnm, dgnm, dhnm,
+		p, dp, radialTerms(g.nMax, r), cosML, sinML, phi, r)
+	field.l = loc
+	return field, err
+}
@@ -0,0 +1,105 @@
+package wmm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// The test table combines the WMM2015v2 and WMM2020 coefficients in the IGRF format,
+// so that at the epochs and after the last epoch it must reproduce the WMM fields.
+func TestIGRFAgainstWMM(t *testing.T) {
+	g, err := LoadIGRF("testdata/IGRF_WMM_TEST.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Epochs)!=2 || g.Epochs[0]!=2015 || g.Epochs[1]!=2020 || g.MaxDegree()!=12 {
+		t.Fatalf("bad epochs %v or degree %d", g.Epochs, g.MaxDegree())
+	}
+	w15, _ := LoadModel("testdata/WMM2015v2.COF")
+	w20, _ := LoadModel("testdata/WMM2020.COF")
+
+	locs := []egm96.Location{
+		egm96.NewLocationGeodetic(80, 0, 0),
+		egm96.NewLocationGeodetic(-12.25, 82.75, 10000),
+		egm96.NewLocationGeodetic(-80, 240, 100e3),
+	}
+	for _, loc := range locs {
+		lat, lng, _ := loc.Geodetic()
+		name := fmt.Sprintf("(%4.1f,%5.1f)", lat/egm96.Deg, lng/egm96.Deg)
+
+		// At and after the last epoch, the WMM2020 main field and secular variation apply
+		for _, y := range []DecimalYear{2020, 2022.5} {
+			magG, err := g.MagneticField(loc, y.ToTime())
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			magW, _ := w20.MagneticField(loc, y.ToTime())
+			compareFields(fmt.Sprintf("%s %6.1f", name, y), magG, magW, t)
+		}
+
+		// At the first epoch, the WMM2015v2 main field applies
+		magG, _ := g.MagneticField(loc, DecimalYear(2015).ToTime())
+		magW, _ := w15.MagneticField(loc, DecimalYear(2015).ToTime())
+		xG, yG, zG, dxG, dyG, dzG := magG.Spherical()
+		xW, yW, zW, _, _, _ := magW.Spherical()
+		testDiff(name+" 2015 X", xG, xW, 1e-6, t)
+		testDiff(name+" 2015 Y", yG, yW, 1e-6, t)
+		testDiff(name+" 2015 Z", zG, zW, 1e-6, t)
+
+		// Between epochs, the field is interpolated linearly
+		mag20, _ := w20.MagneticField(loc, DecimalYear(2020).ToTime())
+		x20, y20, z20, _, _, _ := mag20.Spherical()
+		testDiff(name+" 2015 DX", dxG, (x20-xW)/5, 1e-6, t)
+		testDiff(name+" 2015 DY", dyG, (y20-yW)/5, 1e-6, t)
+		testDiff(name+" 2015 DZ", dzG, (z20-zW)/5, 1e-6, t)
+		magG, _ = g.MagneticField(loc, DecimalYear(2017.5).ToTime())
+		xG, yG, zG, _, _, _ = magG.Spherical()
+		testDiff(name+" 2017.5 X", xG, (xW+x20)/2, 1e-6, t)
+		testDiff(name+" 2017.5 Y", yG, (yW+y20)/2, 1e-6, t)
+		testDiff(name+" 2017.5 Z", zG, (zW+z20)/2, 1e-6, t)
+	}
+
+	gnm, _, dgnm, _, err := g.Coefficients(1, 0, DecimalYear(2017.5).ToTime())
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	testDiff("G(1,0) at 2017.5", gnm, (-29438.2-29404.5)/2, eps, t)
+	testDiff("DG(1,0) at 2017.5", dgnm, (-29404.5+29438.2)/5, eps, t)
+
+	for _, y := range []DecimalYear{2014.9, 2025.1} {
+		if _, err = g.MagneticField(locs[0], y.ToTime()); err == nil {
+			t.Errorf("expected an error for %v", y)
+		}
+	}
+}
+
+func compareFields(name string, actual, expected MagneticField, t *testing.T) {
+	x, y, z, dx, dy, dz := actual.Spherical()
+	xE, yE, zE, dxE, dyE, dzE := expected.Spherical()
+	testDiff(name+" X", x, xE, 1e-6, t)
+	testDiff(name+" Y", y, yE, 1e-6, t)
+	testDiff(name+" Z", z, zE, 1e-6, t)
+	testDiff(name+" DX", dx, dxE, 1e-6, t)
+	testDiff(name+" DY", dy, dyE, 1e-6, t)
+	testDiff(name+" DZ", dz, dzE, 1e-6, t)
+	testDiff(name+" D", actual.D(), expected.D(), 1e-9, t)
+	testDiff(name+" I", actual.I(), expected.I(), 1e-9, t)
+}
+
+func TestReadIGRFBad(t *testing.T) {
+	tables := []string{
+		"",
+		"g 1 0 -31543 -31464 5.7\n",
+		"g/h n m 1900.0 1905.0 1905-10\ng 1 0 -31543 5.7\n",
+		"g/h n m 1900.0 1905.0 1905-10\ng 1 0 -31543 x 5.7\n",
+		"g/h n m 1900.0 1905.0 1905-10\ng 1 2 -31543 -31464 5.7\n",
+	}
+	for _, table := range tables {
+		if _, err := ReadIGRF(strings.NewReader(table)); err == nil {
+			t.Errorf("ReadIGRF incorrectly thought it could parse %q", table)
+		}
+	}
+}
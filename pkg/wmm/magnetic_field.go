@@ -5,6 +5,8 @@
 // WMM is the magnetic model component of the World Geodetic System (WGS84).
 // It consists of n=m=12 spherical harmonic coefficients as published by the
 // National Geospatial-Intelligence Agency (NGA).
+// Coefficient files of any degree in the same format, such as the
+// high-resolution WMMHR, can also be loaded and evaluated.
 //
 // This model evaluates all magnetic field components and their rates of change
 // for any location on the Earth's surface.  These field components include the
@@ -18,26 +20,22 @@ import (
 	"time"
 
 	"github.com/westphae/geomag/pkg/egm96"
-	"github.com/westphae/geomag/pkg/polynomial"
 )
 
-const (
-	AGeo  = 6371200 // Geomagnetic Reference Radius
-	errX  = 131     // WMM global average X error, nT
-	errY  = 94      // WMM global average Y error, nT
-	errZ  = 157     // WMM global average Z error, nT
-	errH  = 128     // WMM global average H error, nT
-	errF  = 148     // WMM global average F error, nT
-	errI  = 0.21    // WMM global average I error, º
-	errDA = 0.26    // WMM rough global average D error away from poles, º
-	errDB = 5625    // WMM average H uncertainty scale near the poles, nT
-)
+const AGeo = 6371200 // Geomagnetic Reference Radius
 
 // MagneticField represents a geomagnetic field and its rate of change.
 type MagneticField struct {
 	l          egm96.Location
 	x, y, z    float64
 	dx, dy, dz float64
+	g, dg      [6]float64  // Spherical gradient NN, EE, DD, NE, ND, ED and its rate of change, nT/km
+	em         ErrorModel  // Error model of the Model which calculated the field
+}
+
+// Location returns the location at which the magnetic field was calculated.
+func (m MagneticField) Location() (loc egm96.Location) {
+	return m.l
 }
 
 // Ellipsoidal returns the magnetic field in ellipsoidal coordinate axes.
@@ -111,6 +109,9 @@ func (m MagneticField) I() (f float64) {
 //  TrueNorth := Magnetic_North + d
 //
 // The return value is in degrees.
+//
+// See package heading for conversions between true, magnetic, grid and
+// compass headings.
 func (m MagneticField) D() (f float64) {
 	x, y, _, _, _, _ := m.Ellipsoidal()
 	return math.Atan2(y, x) / egm96.Deg
@@ -180,43 +181,49 @@ func (m MagneticField) DGV() (f float64) {
 // ErrX returns the uncertainty in the X component of the magnetic field.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrX() (f float64) {
-	return errX
+	return m.Uncertainty().X
 }
 
 // ErrY returns the uncertainty in the Y component of the magnetic field.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrY() (f float64) {
-	return errY
+	return m.Uncertainty().Y
 }
 
 // ErrZ returns the uncertainty in the Z component of the magnetic field.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrZ() (f float64) {
-	return errZ
+	return m.Uncertainty().Z
 }
 
 // ErrF returns the uncertainty in the total magnetic field F.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrF() (f float64) {
-	return errF
+	return m.Uncertainty().F
 }
 
 // ErrH returns the uncertainty in the horizontal component H of the magnetic field.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrH() (f float64) {
-	return errH
+	return m.Uncertainty().H
 }
 
 // ErrI returns the uncertainty in the inclination I of the magnetic field.
 //
 // The WMM specifies this uncertainty as an average over the global surface.
+// See Uncertainty.
 func (m MagneticField) ErrI() (f float64) {
-	return errI
+	return m.Uncertainty().I
 }
 
 // ErrD returns the uncertainty in the Declination of the magnetic field at the given location.
@@ -224,16 +231,11 @@ func (m MagneticField) ErrI() (f float64) {
 // All other reported model uncertainties are given as the surface average.
 // Because the H field can be close to zero near the poles,
 // the D uncertainty can become very large and must be reported by location.
+// See Uncertainty.
 func (m MagneticField) ErrD() (f float64) {
-	h := m.H()
-	return math.Sqrt(errDA*errDA + errDB*errDB/(h*h))
+	return m.Uncertainty().D
 }
 
-var (
-	curLoc   egm96.Location // Spherical
-	curField MagneticField
-)
-
 func init() {
 	_ = LoadWMMCOF("")
 }
@@ -248,58 +250,188 @@ func init() {
 // coefficients. The function will still return the calculated field in these
 // cases.  The error is informational.
 //
-// This function caches the WMM coefficients for computational speed.
-// TODO: implement this and check the description is correct. Use benchmarking
-// It also caches intermediate computational steps for speed in looping over
-// locations.
-// The innermost loop should be over time, followed in order by height,
-// latitude, and finally longitude.
+// The returned error is a *HeightError or a *DateError, which may be tested
+// with errors.Is against ErrHeightOutOfRange and ErrDateOutOfRange.
+// If both the height and the time are invalid, the *DateError is returned.
+//
+// This function caches the field at the most recently requested location,
+// so looping over times at a fixed location is fast.
+// To calculate the field over many locations, use CalculateWMMMagneticFieldGrid,
+// which reuses intermediate computational steps across the grid.
 //
 // See the description of LoadWMMCOF for the validity period of the
 // default (current) coefficients file.
+//
+// The field is calculated with the default Model.
+// It is safe to call concurrently from multiple goroutines.
 func CalculateWMMMagneticField(loc egm96.Location, t time.Time) (field MagneticField, err error) {
-	// TODO: give an err if height<-1000m or height>850000m.
-	if !loc.Equals(curLoc) {
-		curLoc = loc
-		curField = *new(MagneticField)
-		phi, lambda, hh := loc.Spherical()
-		sinPhi := math.Sin(phi)
-		cosPhi := math.Cos(phi)
-		var g, h, dg, dh float64
-		for n:=1; n<=MaxLegendreOrder; n++ {
-			nn := float64(n+1)
-			// if height varies, recalculate from here
-			f := polynomial.Pow(AGeo/hh, n+2)
-			for m:=0; m<=n; m++ {
-				mf := float64(m)
-				// if latitude varies, recalculate from here
-				p := polynomial.LegendreFunction(n, m, sinPhi)
-				q := polynomial.LegendreFunction(n+1, m, sinPhi)
-				if m>0 {
-					p *= math.Sqrt(2/polynomial.FactorialRatioFloat(n+m, n-m))
-					q *= math.Sqrt(2/polynomial.FactorialRatioFloat(n+m, n-m))
-				}
-				dp := nn*math.Tan(phi)*p - (nn-mf)/cosPhi*q
-				g, h, dg, dh, err = GetWMMCoefficients(n, m, ValidDate)
-				// if longitude varies, recalculate from here
-				sinMLambda := math.Sin(mf*lambda)
-				cosMLambda := math.Cos(mf*lambda)
-				curField.x += -f*(g*cosMLambda+h*sinMLambda)*dp
-				curField.y += f/cosPhi*mf*(g*sinMLambda-h*cosMLambda)*p
-				curField.z += -nn*f*(g*cosMLambda+h*sinMLambda)*p
-				curField.dx += -f*(dg*cosMLambda+dh*sinMLambda)*dp
-				curField.dy += f/cosPhi*mf*(dg*sinMLambda-dh*cosMLambda)*p
-				curField.dz += -nn*f*(dg*cosMLambda+dh*sinMLambda)*p
-			}
-		}
+	return DefaultModel().MagneticField(loc, t)
+}
+
+// MagneticField returns the magnetic field of the Model at the input location
+// at the input time.
+//
+// It behaves as CalculateWMMMagneticField, but uses the coefficients of
+// the Model rather than those of the default Model.
+// It is safe to call concurrently from multiple goroutines.
+func (w *Model) MagneticField(loc egm96.Location, t time.Time) (field MagneticField, err error) {
+	w.mu.Lock()
+	cached := w.cached && loc.Equals(w.curLoc)
+	curField := w.curField
+	w.mu.Unlock()
+
+	if err = w.checkDate(t); err == nil {
+		_, _, h := loc.Geodetic()
+		err = checkHeight(h)
 	}
-	dt := float64(TimeToDecimalYears(t) - TimeToDecimalYears(ValidDate))
+	if !cached {
+		curField = w.fieldAtValidDate(loc)
+		w.mu.Lock()
+		w.cached = true
+		w.curLoc = loc
+		w.curField = curField
+		w.mu.Unlock()
+	}
+
+	dt := float64(TimeToDecimalYears(t) - TimeToDecimalYears(w.ValidDate))
+	field = curField.extrapolated(dt)
 	field.l = loc
-	field.x = curField.x + dt*curField.dx
-	field.y = curField.y + dt*curField.dy
-	field.z = curField.z + dt*curField.dz
-	field.dx = curField.dx
-	field.dy = curField.dy
-	field.dz = curField.dz
 	return field, err
 }
+
+// fieldAtValidDate calculates the field at the input location at the
+// ValidDate of the Model.
+func (w *Model) fieldAtValidDate(loc egm96.Location) (field MagneticField) {
+	phi, lambda, r := loc.Spherical()
+	p, dp := legendreTerms(w.nMax, phi)
+	cosML, sinML := longitudeTerms(w.nMax, lambda)
+	field = w.sumField(p, dp, radialTerms(w.nMax, r), cosML, sinML, phi, r)
+	field.l = loc
+	return field
+}
+
+// legendreTerms returns the Schmidt semi-normalized associated Legendre
+// functions P(n,m) evaluated at sin(phi) and their derivatives dP(n,m) with
+// respect to the spherical latitude phi, up to degree nMax.
+//
+// These depend only on the spherical latitude.
+// They are calculated by the standard recursion in the degree n, which
+// remains numerically stable for high-degree models.
+func legendreTerms(nMax int, phi float64) (p, dp [][]float64) {
+	// In terms of the colatitude θ, cos(θ)=sin(φ) and sin(θ)=cos(φ)
+	cosT, sinT := math.Sincos(phi)
+	p = make([][]float64, nMax+1)
+	dp = make([][]float64, nMax+1)
+	p[0] = []float64{1}
+	dp[0] = []float64{0}
+	for n:=1; n<=nMax; n++ {
+		p[n] = make([]float64, n+1)
+		dp[n] = make([]float64, n+1)
+		nf := float64(n)
+		for m:=0; m<n; m++ {
+			mf := float64(m)
+			// Derivatives are with respect to θ here and changed to φ below
+			a := (2*nf-1)/math.Sqrt(nf*nf-mf*mf)
+			p[n][m] = a*cosT*p[n-1][m]
+			dp[n][m] = a*(cosT*dp[n-1][m] - sinT*p[n-1][m])
+			if n>=m+2 {
+				b := math.Sqrt(((nf-1)*(nf-1)-mf*mf)/(nf*nf-mf*mf))
+				p[n][m] -= b*p[n-2][m]
+				dp[n][m] -= b*dp[n-2][m]
+			}
+		}
+		c := 1.0
+		if n>1 {
+			c = math.Sqrt((2*nf-1)/(2*nf))
+		}
+		p[n][n] = c*sinT*p[n-1][n-1]
+		dp[n][n] = c*(sinT*dp[n-1][n-1] + cosT*p[n-1][n-1])
+	}
+	for n:=1; n<=nMax; n++ {
+		for m:=0; m<=n; m++ {
+			dp[n][m] = -dp[n][m]
+		}
+	}
+	return p, dp
+}
+
+// radialTerms returns the factors (a/r)^(n+2), which depend only on the
+// distance r from the Earth's center.
+func radialTerms(nMax int, r float64) (f []float64) {
+	f = make([]float64, nMax+1)
+	f[0] = AGeo/r*AGeo/r
+	for n:=1; n<=nMax; n++ {
+		f[n] = f[n-1]*AGeo/r
+	}
+	return f
+}
+
+// longitudeTerms returns cos(m*lambda) and sin(m*lambda), which depend only
+// on the longitude lambda.
+func longitudeTerms(nMax int, lambda float64) (cosML, sinML []float64) {
+	cosML = make([]float64, nMax+1)
+	sinML = make([]float64, nMax+1)
+	for m:=0; m<=nMax; m++ {
+		sinML[m], cosML[m] = math.Sincos(float64(m)*lambda)
+	}
+	return cosML, sinML
+}
+
+// sumField sums the spherical harmonic expansion of the field at ValidDate
+// given the precomputed Legendre, radial and longitude terms.
+func (w *Model) sumField(p, dp [][]float64, f, cosML, sinML []float64, phi, r float64) (field MagneticField) {
+	field = sumField(w.nMax, w.nMaxSV, w.gnm, w.hnm, w.cDGnm, w.cDHnm, p, dp, f, cosML, sinML, phi, r)
+	field.em = w.Errors
+	return field
+}
+
+// sumField sums the spherical harmonic expansion of the field and its
+// gradient with coefficients gnm, hnm up to degree nMax and of their rates
+// of change with coefficients dgnm, dhnm up to degree nMaxSV,
+// given the precomputed Legendre, radial and longitude terms at the
+// spherical latitude phi and radius r.
+func sumField(nMax, nMaxSV int, gnm, hnm, dgnm, dhnm [][]float64,
+	p, dp [][]float64, f, cosML, sinML []float64, phi, r float64) (field MagneticField) {
+	cosPhi := math.Cos(phi)
+	tanPhi := math.Tan(phi)
+	for n:=1; n<=nMax; n++ {
+		nn := float64(n+1)
+		for m:=0; m<=n; m++ {
+			mf := float64(m)
+			g, h := gnm[n][m], hnm[n][m]
+			gc := g*cosML[m] + h*sinML[m]
+			gs := g*sinML[m] - h*cosML[m]
+			field.x += -f[n]*gc*dp[n][m]
+			field.y += f[n]/cosPhi*mf*gs*p[n][m]
+			field.z += -nn*f[n]*gc*p[n][m]
+			addGradient(&field.g, n, m, gc, gs, f[n], p[n][m], dp[n][m], cosPhi, tanPhi)
+			if n>nMaxSV {
+				continue
+			}
+			dg, dh := dgnm[n][m], dhnm[n][m]
+			dgc := dg*cosML[m] + dh*sinML[m]
+			dgs := dg*sinML[m] - dh*cosML[m]
+			field.dx += -f[n]*dgc*dp[n][m]
+			field.dy += f[n]/cosPhi*mf*dgs*p[n][m]
+			field.dz += -nn*f[n]*dgc*p[n][m]
+			addGradient(&field.dg, n, m, dgc, dgs, f[n], p[n][m], dp[n][m], cosPhi, tanPhi)
+		}
+	}
+	for i := range field.g {
+		field.g[i] *= 1000/r
+		field.dg[i] *= 1000/r
+	}
+	return field
+}
+
+// extrapolated returns the field extrapolated linearly by dt years.
+func (m MagneticField) extrapolated(dt float64) (field MagneticField) {
+	field = m
+	field.x += dt*m.dx
+	field.y += dt*m.dy
+	field.z += dt*m.dz
+	for i := range field.g {
+		field.g[i] += dt*m.dg[i]
+	}
+	return field
+}
@@ -3,12 +3,17 @@ package wmm
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/westphae/geomag/pkg/egm96"
+	"github.com/westphae/geomag/pkg/polynomial"
 )
 
 const (
@@ -339,3 +344,167 @@ func TestAll2020TestValuesFromPaper(t *testing.T) {
 	}
 
 }
+
+func TestModelsSideBySide(t *testing.T) {
+	_ = LoadWMMCOF("")
+	m15, err := LoadModel("testdata/WMM2015v2.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m20, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if COFName!="WMM-2020" || DefaultModel().COFName!="WMM-2020" {
+		t.Errorf("LoadModel changed the default model to %s", COFName)
+	}
+
+	loc15 := egm96.NewLocationGeodetic(80, 0, 0)
+	loc20 := egm96.NewLocationGeodetic(89, -121, 28e3)
+	for i:=0; i<2; i++ {
+		mag, _ := m15.MagneticField(loc15, DecimalYear(2015).ToTime())
+		x, y, z, _, _, _ := mag.Ellipsoidal()
+		testDiff("WMM2015v2 X", x, 6636.6, 0.05, t)
+		testDiff("WMM2015v2 Y", y, -451.9, 0.05, t)
+		testDiff("WMM2015v2 Z", z, 54408.9, 0.05, t)
+
+		mag, _ = m20.MagneticField(loc20, DecimalYear(2020).ToTime())
+		x, y, z, _, _, _ = mag.Ellipsoidal()
+		testDiff("WMM2020 X", x, -575.7, 0.05, t)
+		testDiff("WMM2020 Y", y, -1396.0, 0.05, t)
+		testDiff("WMM2020 Z", z, 56082.3, 0.05, t)
+	}
+
+	g15, _, _, _, _ := m15.Coefficients(1, 0, DecimalYear(2015).ToTime())
+	g20, _, _, _, _ := m20.Coefficients(1, 0, DecimalYear(2020).ToTime())
+	testDiff("WMM2015v2 G(1,0)", g15, -29438.2, eps, t)
+	testDiff("WMM2020 G(1,0)", g20, -29404.5, eps, t)
+}
+
+// TestConcurrentMagneticField is most useful when run with -race.
+func TestConcurrentMagneticField(t *testing.T) {
+	const nGoroutines = 16
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022.5).ToTime()
+	locs := make([]egm96.Location, 24)
+	expected := make([]MagneticField, len(locs))
+	for i := range locs {
+		locs[i] = egm96.NewLocationGeodetic(float64(7*i-80), float64(15*i), float64(1000*i))
+		expected[i], _ = w.MagneticField(locs[i], tt)
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]MagneticField, nGoroutines)
+	for g:=0; g<nGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			results[g] = make([]MagneticField, len(locs))
+			for k := range locs {
+				i := (k+g)%len(locs)
+				if g%2==0 {
+					results[g][i], _ = w.MagneticField(locs[i], tt)
+				} else {
+					results[g][i], _ = CalculateWMMMagneticField(locs[i], tt)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g:=0; g<nGoroutines; g+=2 {
+		for i := range locs {
+			if results[g][i]!=expected[i] {
+				t.Errorf("goroutine %d got %v at location %d, expected %v", g, results[g][i], i, expected[i])
+			}
+		}
+	}
+}
+
+func TestLegendreTermsAgainstPolynomials(t *testing.T) {
+	for _, lat := range []float64{-89.5, -60, -12.25, 0, 30, 45.5, 80} {
+		phi := lat*egm96.Deg
+		p, dp := legendreTerms(MaxLegendreOrder, phi)
+		for n:=1; n<=MaxLegendreOrder; n++ {
+			nn := float64(n+1)
+			for m:=0; m<=n; m++ {
+				pp := polynomial.LegendreFunction(n, m, math.Sin(phi))
+				q := polynomial.LegendreFunction(n+1, m, math.Sin(phi))
+				if m>0 {
+					pp *= math.Sqrt(2/polynomial.FactorialRatioFloat(n+m, n-m))
+					q *= math.Sqrt(2/polynomial.FactorialRatioFloat(n+m, n-m))
+				}
+				dpp := nn*math.Tan(phi)*pp - (nn-float64(m))/math.Cos(phi)*q
+				testDiff(fmt.Sprintf("P(%d,%d) at %5.2f", n, m, lat), p[n][m], pp, 1e-9, t)
+				testDiff(fmt.Sprintf("dP(%d,%d) at %5.2f", n, m, lat), dp[n][m], dpp, 1e-7, t)
+			}
+		}
+	}
+}
+
+func TestHighDegreeModel(t *testing.T) {
+	const nMax = 133
+	data, err := ioutil.ReadFile("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w12, _ := ReadModel(bytes.NewReader(data))
+
+	// Pad WMM2020 with zero main field coefficients without secular variation
+	var buf bytes.Buffer
+	buf.Write(data)
+	for n:=MaxLegendreOrder+1; n<=nMax; n++ {
+		for m:=0; m<=n; m++ {
+			fmt.Fprintf(&buf, "%3d %3d %9.1f %9.1f\n", n, m, 0.0, 0.0)
+		}
+	}
+	wHR, err := ReadModel(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wHR.MaxDegree()!=nMax || wHR.SVDegree()!=MaxLegendreOrder {
+		t.Errorf("expected degrees %d and %d, got %d and %d",
+			nMax, MaxLegendreOrder, wHR.MaxDegree(), wHR.SVDegree())
+	}
+	if _, _, _, _, err = wHR.Coefficients(nMax, nMax, DecimalYear(2021).ToTime()); err != nil {
+		t.Errorf("unexpected error for n=m=%d: %v", nMax, err)
+	}
+
+	tt := DecimalYear(2021.5).ToTime()
+	for _, lat := range []float64{-89.99, -45, 0, 33.3, 89.99} {
+		loc := egm96.NewLocationGeodetic(lat, 123.4, 5000)
+		mag12, _ := w12.MagneticField(loc, tt)
+		magHR, _ := wHR.MagneticField(loc, tt)
+		x12, y12, z12, dx12, dy12, dz12 := mag12.Spherical()
+		xHR, yHR, zHR, dxHR, dyHR, dzHR := magHR.Spherical()
+		testDiff("padded X", xHR, x12, 1e-6, t)
+		testDiff("padded Y", yHR, y12, 1e-6, t)
+		testDiff("padded Z", zHR, z12, 1e-6, t)
+		testDiff("padded DX", dxHR, dx12, 1e-9, t)
+		testDiff("padded DY", dyHR, dy12, 1e-9, t)
+		testDiff("padded DZ", dzHR, dz12, 1e-9, t)
+	}
+
+	// Crustal-scale coefficients to high degree must not blow up, even near the poles
+	buf.Reset()
+	buf.Write(data[:bytes.IndexByte(data, '\n')+1])
+	rnd := rand.New(rand.NewSource(1))
+	for n:=1; n<=nMax; n++ {
+		for m:=0; m<=n; m++ {
+			fmt.Fprintf(&buf, "%3d %3d %9.2f %9.2f\n", n, m, 20*rnd.NormFloat64(), 20*rnd.NormFloat64())
+		}
+	}
+	wRnd, err := ReadModel(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lat := range []float64{-89.99, -70, 0, 45, 89.99} {
+		mag, _ := wRnd.MagneticField(egm96.NewLocationGeodetic(lat, -77, 0), tt)
+		if f := mag.F(); math.IsNaN(f) || math.IsInf(f, 0) || f>1e6 {
+			t.Errorf("unstable field %v at latitude %v", f, lat)
+		}
+	}
+}
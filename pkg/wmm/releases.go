@@ -0,0 +1,106 @@
+package wmm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// releases lists the WMM releases embedded in the package, oldest first.
+// Each is stored as an asset named after the release, e.g. WMM2020.COF.
+var releases = embeddedReleases()
+
+var (
+	releaseModels   = make(map[string]*Model)
+	releaseModelsMu sync.Mutex
+)
+
+// Releases returns the names of the WMM releases embedded in the package,
+// oldest first, e.g. "WMM2015v1", "WMM2015v2", "WMM2020".
+func Releases() (names []string) {
+	return append([]string(nil), releases...)
+}
+
+// LoadRelease returns the Model for the named embedded WMM release, e.g.
+// "WMM2015v2", to pin calculations to a specific release.
+// The name is not case-sensitive and may also be given as in the header of
+// the COF file, e.g. "WMM-2015v2".
+//
+// The returned Model is shared by all callers requesting the same release.
+func LoadRelease(name string) (w *Model, err error) {
+	key := strings.ToUpper(strings.Replace(strings.TrimSuffix(name, ".COF"), "-", "", -1))
+	for _, r := range releases {
+		if strings.ToUpper(r)==key {
+			return loadRelease(r)
+		}
+	}
+	// The COF header of the first version of a release has no version suffix
+	for _, r := range releases {
+		if strings.ToUpper(r)==key+"V1" {
+			return loadRelease(r)
+		}
+	}
+	return nil, fmt.Errorf("unknown WMM release %s, the embedded releases are %s",
+		name, strings.Join(releases, ", "))
+}
+
+// embeddedReleases returns the names of the release assets, i.e. every COF
+// asset but the default WMM.COF, which sort by name in order of release.
+func embeddedReleases() (names []string) {
+	for a := range _bindata {
+		if strings.HasPrefix(a, "WMM") && strings.HasSuffix(a, ".COF") && a!="WMM.COF" {
+			names = append(names, strings.TrimSuffix(a, ".COF"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func loadRelease(name string) (w *Model, err error) {
+	releaseModelsMu.Lock()
+	defer releaseModelsMu.Unlock()
+	if w, ok := releaseModels[name]; ok {
+		return w, nil
+	}
+	data, err := getAsset(name+".COF")
+	if err != nil {
+		return nil, err
+	}
+	if w, err = parseCOF(data, name+".COF"); err != nil {
+		return nil, err
+	}
+	releaseModels[name] = w
+	return w, nil
+}
+
+// ModelForTime returns the Model of the embedded WMM release whose validity
+// period contains the input time.
+//
+// Where the validity periods of releases overlap, e.g. for an out-of-cycle
+// release such as WMM2015v2, the most recently released one is chosen.
+// A *DateError is returned if no embedded release is valid at the input time.
+func ModelForTime(t time.Time) (w *Model, err error) {
+	var first, last *Model
+	for _, r := range releases {
+		m, err := loadRelease(r)
+		if err != nil {
+			return nil, err
+		}
+		if first==nil || m.ValidDate.Before(first.ValidDate) {
+			first = m
+		}
+		if last==nil || m.Epoch+5>last.Epoch+5 {
+			last = m
+		}
+		if m.checkDate(t)==nil && (w==nil || m.ValidDate.After(w.ValidDate)) {
+			w = m
+		}
+	}
+	if w==nil {
+		return nil, &DateError{Date: t, Start: first.ValidDate, End: (last.Epoch+5).ToTime(),
+			Model: "embedded WMM release"}
+	}
+	return w, nil
+}
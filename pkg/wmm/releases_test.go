@@ -0,0 +1,95 @@
+package wmm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModelForTime(t *testing.T) {
+	ts := []time.Time{
+		time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2018, 9, 17, 0, 0, 0, 0, time.UTC),
+		time.Date(2018, 9, 18, 0, 0, 0, 0, time.UTC),
+		time.Date(2019, 12, 9, 0, 0, 0, 0, time.UTC),
+		time.Date(2019, 12, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	names := []string{"WMM-2015", "WMM-2015", "WMM-2015v2", "WMM-2015v2", "WMM-2020", "WMM-2020"}
+
+	for i, tt := range ts {
+		w, err := ModelForTime(tt)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", tt, err)
+			continue
+		}
+		if w.COFName!=names[i] {
+			t.Errorf("%s%v got %s, expected %s%s", red, tt, w.COFName, names[i], reset)
+		} else {
+			t.Logf("%s%v correctly got %s%s", green, tt, w.COFName, reset)
+		}
+	}
+
+	for _, tt := range []time.Time{
+		time.Date(2014, 12, 14, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := ModelForTime(tt); err == nil {
+			t.Errorf("%sexpected an error for %v%s", red, tt, reset)
+		} else {
+			t.Logf("%scorrectly got error %v%s", green, err, reset)
+		}
+	}
+}
+
+func TestLoadRelease(t *testing.T) {
+	inps := []string{"WMM2015v1", "wmm2015V2", "WMM-2015", "WMM-2015v2", "WMM2020.COF", "WMM-2020"}
+	names := []string{"WMM-2015", "WMM-2015v2", "WMM-2015", "WMM-2015v2", "WMM-2020", "WMM-2020"}
+
+	for i, inp := range inps {
+		w, err := LoadRelease(inp)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", inp, err)
+			continue
+		}
+		if w.COFName!=names[i] {
+			t.Errorf("%s%s got %s, expected %s%s", red, inp, w.COFName, names[i], reset)
+		}
+	}
+
+	w1, _ := LoadRelease("WMM2020")
+	w2, _ := ModelForTime(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if w1!=w2 {
+		t.Error("expected the same Model for the same release")
+	}
+
+	for _, inp := range []string{"WMM2010", "WMM", "IGRF13"} {
+		if _, err := LoadRelease(inp); err == nil {
+			t.Errorf("%sexpected an error for %s%s", red, inp, reset)
+		}
+	}
+	if len(Releases())!=3 {
+		t.Errorf("expected 3 releases, got %v", Releases())
+	}
+}
+
+func TestEmbeddedReleases(t *testing.T) {
+	// Every release embedded, e.g. WMM2010 or WMM2025 once added to bindata.go, loads by name and date
+	for _, r := range Releases() {
+		w, err := LoadRelease(r)
+		if err != nil {
+			t.Errorf("unexpected error loading %s: %v", r, err)
+			continue
+		}
+		name := strings.Replace(strings.TrimSuffix(r, "v1"), "WMM", "WMM-", 1)
+		if w.COFName!=name {
+			t.Errorf("%s%s got %s, expected %s%s", red, r, w.COFName, name, reset)
+		}
+		wt, err := ModelForTime(w.ValidDate.AddDate(0, 0, 1))
+		if err != nil {
+			t.Errorf("unexpected error for %s at %v: %v", r, w.ValidDate, err)
+		} else if wt!=w {
+			t.Errorf("%sexpected %s just after its release, got %s%s", red, w.COFName, wt.COFName, reset)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package wmm
+
+import (
+	"math"
+	"strings"
+)
+
+// ErrorModel holds the coefficients of the error model published with a WMM
+// release, which give the one standard deviation uncertainties of the model.
+//
+// All uncertainties other than that of the declination D are given as
+// averages over the global surface.
+// Because the H field can be close to zero near the poles, the D uncertainty
+// depends on location and is calculated as sqrt(DA² + (DB/H)²) in degrees.
+//
+// The zero ErrorModel is that of a model without a published error model,
+// for which all uncertainties are unknown and reported as NaN.
+type ErrorModel struct {
+	X  float64 // Global average X uncertainty, nT
+	Y  float64 // Global average Y uncertainty, nT
+	Z  float64 // Global average Z uncertainty, nT
+	H  float64 // Global average H uncertainty, nT
+	F  float64 // Global average F uncertainty, nT
+	I  float64 // Global average I uncertainty, º
+	DA float64 // Rough global average D uncertainty away from the poles, º
+	DB float64 // Average H uncertainty scale near the poles, nT·º
+}
+
+// Uncertainty holds the one standard deviation uncertainties of all components
+// of a MagneticField at its location.
+type Uncertainty struct {
+	X, Y, Z, H, F float64 // Field component uncertainties, nT
+	I, D          float64 // Inclination and declination uncertainties, º
+}
+
+// errorModels holds the published error models, keyed by the model name
+// given in the COF file header.
+// Out-of-cycle versions of a release, e.g. WMM-2015v2, share its error model.
+var errorModels = map[string]ErrorModel{
+	"WMM-2015": {X: 138, Y: 89, Z: 165, H: 133, F: 152, I: 0.22, DA: 0.23, DB: 5430},
+	"WMM-2020": {X: 131, Y: 94, Z: 157, H: 128, F: 148, I: 0.21, DA: 0.26, DB: 5625},
+}
+
+// errorModelFor returns the error model published with the named model,
+// or the zero ErrorModel if none is known.
+func errorModelFor(name string) (em ErrorModel) {
+	for k, em := range errorModels {
+		if strings.HasPrefix(strings.ToUpper(name), k) {
+			return em
+		}
+	}
+	return ErrorModel{}
+}
+
+// Uncertainty returns the uncertainties of all components of the magnetic
+// field at its location, according to the error model of the Model which
+// calculated it.
+//
+// The IGRF has no published error model, so all uncertainties of a field
+// calculated by an IGRFModel, or by a Model whose Errors are the zero
+// ErrorModel, are unknown and are NaN, which may be tested with math.IsNaN.
+func (m MagneticField) Uncertainty() (u Uncertainty) {
+	if m.em==(ErrorModel{}) {
+		nan := math.NaN()
+		return Uncertainty{X: nan, Y: nan, Z: nan, H: nan, F: nan, I: nan, D: nan}
+	}
+	h := m.H()
+	return Uncertainty{
+		X: m.em.X,
+		Y: m.em.Y,
+		Z: m.em.Z,
+		H: m.em.H,
+		F: m.em.F,
+		I: m.em.I,
+		D: math.Sqrt(m.em.DA*m.em.DA + m.em.DB*m.em.DB/(h*h)),
+	}
+}
@@ -0,0 +1,96 @@
+package wmm
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestUncertainty(t *testing.T) {
+	loc := egm96.NewLocationGeodetic(30, -88.51, 10)
+	fns := []string{"testdata/WMM2015v1.COF", "testdata/WMM2015v2.COF", "testdata/WMM2020.COF"}
+	ems := []ErrorModel{errorModels["WMM-2015"], errorModels["WMM-2015"], errorModels["WMM-2020"]}
+	for i, fn := range fns {
+		w, err := LoadModel(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Errors!=ems[i] {
+			t.Errorf("%s%s got error model %+v, expected %+v%s", red, fn, w.Errors, ems[i], reset)
+		}
+		mag, _ := w.MagneticField(loc, w.ValidDate)
+		u := mag.Uncertainty()
+		testDiff(fn+" ErrX", mag.ErrX(), ems[i].X, 0, t)
+		testDiff(fn+" ErrY", mag.ErrY(), ems[i].Y, 0, t)
+		testDiff(fn+" ErrZ", mag.ErrZ(), ems[i].Z, 0, t)
+		testDiff(fn+" ErrH", mag.ErrH(), ems[i].H, 0, t)
+		testDiff(fn+" ErrF", mag.ErrF(), ems[i].F, 0, t)
+		testDiff(fn+" ErrI", mag.ErrI(), ems[i].I, 0, t)
+		testDiff(fn+" ErrD", mag.ErrD(), u.D, 0, t)
+		h := mag.H()
+		testDiff(fn+" D", u.D, math.Sqrt(ems[i].DA*ems[i].DA+ems[i].DB*ems[i].DB/(h*h)), 1e-12, t)
+
+		// The grid carries the same error model
+		grid, _ := w.MagneticFieldGrid([]float64{30}, []float64{-88.51}, []float64{10}, []time.Time{w.ValidDate})
+		if grid.At(0, 0, 0, 0).Uncertainty()!=u {
+			t.Errorf("%s%s grid uncertainty %+v differs from %+v%s", red, fn, grid.At(0, 0, 0, 0).Uncertainty(), u, reset)
+		}
+	}
+
+	// The declination uncertainty grows near the magnetic poles
+	mag, _ := CalculateWMMMagneticField(egm96.NewLocationGeodetic(86, 160, 0), DecimalYear(2022).ToTime())
+	if mag.ErrD()<10*mag.Uncertainty().I {
+		t.Errorf("%sexpected a large declination uncertainty near the pole, got %v%s", red, mag.ErrD(), reset)
+	}
+
+	// The IGRF has no error model
+	g, err := LoadIGRF("testdata/IGRF_WMM_TEST.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mag, _ = g.MagneticField(loc, DecimalYear(2017).ToTime())
+	if !unknownUncertainty(mag.Uncertainty()) {
+		t.Errorf("%sexpected unknown uncertainties for the IGRF, got %+v%s", red, mag.Uncertainty(), reset)
+	}
+
+	// An unknown model has the zero error model and unknown uncertainties, not zero
+	if errorModelFor("WMMHR-2025")!=(ErrorModel{}) {
+		t.Errorf("%sexpected the zero error model for an unknown model%s", red, reset)
+	}
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Errors = ErrorModel{}
+	mag, _ = w.MagneticField(loc, w.ValidDate)
+	if !unknownUncertainty(mag.Uncertainty()) || !math.IsNaN(mag.ErrD()) {
+		t.Errorf("%sexpected unknown uncertainties for the zero error model, got %+v%s", red, mag.Uncertainty(), reset)
+	}
+	custom, err := ReadModel(strings.NewReader("2020.0 CUSTOM 12/10/2019\n 1 0 -30000.0 0.0 0.0 0.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mag, _ = custom.MagneticField(loc, custom.ValidDate)
+	if !unknownUncertainty(mag.Uncertainty()) {
+		t.Errorf("%sexpected unknown uncertainties for a custom model, got %+v%s", red, mag.Uncertainty(), reset)
+	}
+
+	// A field keeps the error model it was calculated with
+	w.Errors = errorModels["WMM-2020"]
+	mag, _ = w.MagneticField(egm96.NewLocationGeodetic(-20, 40, 0), w.ValidDate)
+	w.Errors.X = 1
+	testDiff("ErrX after changing the Model's error model", mag.ErrX(), errorModels["WMM-2020"].X, 0, t)
+}
+
+// unknownUncertainty reports whether all uncertainties of u are unknown, i.e. NaN.
+func unknownUncertainty(u Uncertainty) bool {
+	for _, v := range []float64{u.X, u.Y, u.Z, u.H, u.F, u.I, u.D} {
+		if !math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
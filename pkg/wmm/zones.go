@@ -0,0 +1,192 @@
+package wmm
+
+import (
+	"math"
+	"time"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+// Horizontal field strengths bounding the WMM blackout and caution zones, in nT.
+const (
+	BlackoutH = 2000 // Compasses are unreliable where H is below this
+	CautionH  = 6000 // Compasses should be used with caution where H is below this
+)
+
+// Zone classifies a location by the strength of the horizontal field H there,
+// which determines how reliable a magnetic compass is.
+//
+// Near the magnetic poles H becomes small, so that the declination is
+// poorly determined and the WMM declination uncertainty becomes large.
+// The WMM defines a blackout zone where H < 2000 nT, in which compasses are
+// unreliable, surrounded by a caution zone where 2000 nT <= H < 6000 nT.
+type Zone int
+
+const (
+	NormalZone   Zone = iota // H >= 6000 nT
+	CautionZone              // 2000 nT <= H < 6000 nT
+	BlackoutZone             // H < 2000 nT
+)
+
+func (z Zone) String() string {
+	switch z {
+	case NormalZone:
+		return "Normal"
+	case CautionZone:
+		return "Caution"
+	case BlackoutZone:
+		return "Blackout"
+	}
+	return "Unknown"
+}
+
+// threshold returns the value of H bounding the zone, in nT.
+func (z Zone) threshold() (h float64) {
+	if z==BlackoutZone {
+		return BlackoutH
+	}
+	return CautionH
+}
+
+// Zone returns the WMM zone, blackout, caution or normal, of the magnetic field.
+func (m MagneticField) Zone() (z Zone) {
+	h := m.H()
+	switch {
+	case h<BlackoutH:
+		return BlackoutZone
+	case h<CautionH:
+		return CautionZone
+	}
+	return NormalZone
+}
+
+// ZoneBoundaries returns the boundaries of the input zone around the north
+// and south magnetic poles at the input time, calculated with the default Model.
+// See Model.ZoneBoundaries.
+func ZoneBoundaries(zone Zone, t time.Time) (north, south []egm96.Location, err error) {
+	return DefaultModel().ZoneBoundaries(zone, t)
+}
+
+// ZoneBoundaries returns the boundaries of the input zone, BlackoutZone or
+// CautionZone, around the north and south magnetic poles at the input time,
+// e.g. for drawing on a map.
+//
+// Each boundary is a closed ring of locations on the WGS84 ellipsoid at
+// one degree intervals of bearing from the point of weakest H, on which
+// H equals the threshold of the zone.
+// The first location is repeated at the end to close the ring.
+// Bearings on which H does not reach the threshold within 45º are left out,
+// and the boundary is nil if it is reached on none.
+//
+// As for MagneticField, an informational *DateError is returned if the
+// requested time is outside the validity period of the Model, but the
+// boundaries are still calculated.
+func (w *Model) ZoneBoundaries(zone Zone, t time.Time) (north, south []egm96.Location, err error) {
+	if zone!=BlackoutZone && zone!=CautionZone {
+		zone = CautionZone
+	}
+	err = w.checkDate(t)
+	north = w.zoneBoundary(zone.threshold(), 1, t)
+	south = w.zoneBoundary(zone.threshold(), -1, t)
+	return north, south, err
+}
+
+// zoneBoundary returns the ring on which H equals h0 around the point of
+// weakest H in the northern (sgn=1) or southern (sgn=-1) hemisphere.
+func (w *Model) zoneBoundary(h0, sgn float64, t time.Time) (ring []egm96.Location) {
+	const (
+		maxDist = 45   // Furthest distance from the weakest H to search, º
+		dDist   = 0.25 // Step along each bearing when searching for the boundary, º
+	)
+
+	hAt := func(lat, lng float64) (h float64) {
+		m, _ := w.MagneticField(egm96.NewLocationGeodetic(lat, lng, 0), t)
+		return m.H()
+	}
+
+	lat0, lng0 := w.weakestHIn(sgn, t)
+	if hAt(lat0, lng0)>=h0 {
+		return nil
+	}
+
+	// Along each bearing, step out until H reaches h0, then bisect.
+	// Bearings on which H stays below h0 out to maxDist are skipped.
+	for b:=0; b<360; b++ {
+		lo, hi := 0.0, 0.0
+		for hi<maxDist {
+			lo, hi = hi, hi+dDist
+			if hAt(destination(lat0, lng0, float64(b), hi))>=h0 {
+				break
+			}
+		}
+		if hAt(destination(lat0, lng0, float64(b), hi))<h0 {
+			continue
+		}
+		for i:=0; i<20; i++ {
+			mid := (lo+hi)/2
+			if hAt(destination(lat0, lng0, float64(b), mid))<h0 {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		lat, lng := destination(lat0, lng0, float64(b), (lo+hi)/2)
+		ring = append(ring, egm96.NewLocationGeodetic(lat, lng, 0))
+	}
+	if len(ring)==0 {
+		return nil
+	}
+	return append(ring, ring[0])
+}
+
+// weakestHIn returns the latitude and longitude, in degrees, of the point of
+// weakest H at the surface in the northern (sgn=1) or southern (sgn=-1) hemisphere.
+func (w *Model) weakestHIn(sgn float64, t time.Time) (lat, lng float64) {
+	// Coarse search over the polar cap, then refine
+	var lats, lngs []float64
+	for i:=0; i<=40; i++ {
+		lats = append(lats, sgn*(50+float64(i)))
+	}
+	for i:=0; i<360; i++ {
+		lngs = append(lngs, float64(i))
+	}
+	lat, lng = w.weakestH(lats, lngs, t)
+	for _, d := range []float64{1, 0.05} {
+		lats, lngs = lats[:0], lngs[:0]
+		for i:=-20; i<=20; i++ {
+			if l := lat+float64(i)*d; l>=-90 && l<=90 {
+				lats = append(lats, l)
+			}
+			lngs = append(lngs, lng+float64(i)*d)
+		}
+		lat, lng = w.weakestH(lats, lngs, t)
+	}
+	return lat, lng
+}
+
+// weakestH returns the latitude and longitude, in degrees, of the point of
+// weakest H on the grid of input latitudes and longitudes.
+func (w *Model) weakestH(lats, lngs []float64, t time.Time) (lat, lng float64) {
+	grid, _ := w.MagneticFieldGrid(lats, lngs, []float64{0}, []time.Time{t})
+	hMin := math.Inf(1)
+	for i := range lats {
+		for j := range lngs {
+			if h := grid.At(i, j, 0, 0).H(); h<hMin {
+				hMin, lat, lng = h, lats[i], lngs[j]
+			}
+		}
+	}
+	return lat, lng
+}
+
+// destination returns the latitude and longitude, in degrees, reached by
+// travelling the angular distance d along a great circle from the input
+// latitude and longitude at bearing b, all in degrees.
+func destination(lat, lng, b, d float64) (lat2, lng2 float64) {
+	phi, lambda := lat*egm96.Deg, lng*egm96.Deg
+	b, d = b*egm96.Deg, d*egm96.Deg
+	phi2 := math.Asin(math.Sin(phi)*math.Cos(d) + math.Cos(phi)*math.Sin(d)*math.Cos(b))
+	lambda2 := lambda + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(phi),
+		math.Cos(d)-math.Sin(phi)*math.Sin(phi2))
+	return phi2/egm96.Deg, math.Mod(lambda2/egm96.Deg+540, 360)-180
+}
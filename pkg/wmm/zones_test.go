@@ -0,0 +1,87 @@
+package wmm
+
+import (
+	"testing"
+
+	"github.com/westphae/geomag/pkg/egm96"
+)
+
+func TestZone(t *testing.T) {
+	hs := []float64{0, 1999.9, 2000, 5999.9, 6000, 20000}
+	zones := []Zone{BlackoutZone, BlackoutZone, CautionZone, CautionZone, NormalZone, NormalZone}
+	for i, h := range hs {
+		m := MagneticField{x: h*0.6, y: -h*0.8, z: 50000}
+		if m.Zone()!=zones[i] {
+			t.Errorf("%sH=%v got zone %s, expected %s%s", red, h, m.Zone(), zones[i], reset)
+		} else {
+			t.Logf("%sH=%v correctly in zone %s%s", green, h, m.Zone(), reset)
+		}
+	}
+}
+
+func TestZoneBoundaries(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022.5).ToTime()
+
+	for _, zone := range []Zone{BlackoutZone, CautionZone} {
+		north, south, err := w.ZoneBoundaries(zone, tt)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		for _, ring := range [][]egm96.Location{north, south} {
+			if len(ring)!=361 || !ring[0].Equals(ring[360]) {
+				t.Errorf("%sexpected a closed ring of 361 locations, got %d%s", red, len(ring), reset)
+				continue
+			}
+			for _, loc := range ring {
+				mag, _ := w.MagneticField(loc, tt)
+				testDiff(zone.String()+" boundary H", mag.H(), zone.threshold(), 0.01, t)
+			}
+		}
+		lat, _, _ := north[0].Geodetic()
+		if lat<0 {
+			t.Errorf("%snorth boundary is in the southern hemisphere%s", red, reset)
+		}
+		lat, _, _ = south[0].Geodetic()
+		if lat>0 {
+			t.Errorf("%ssouth boundary is in the northern hemisphere%s", red, reset)
+		}
+	}
+
+	// The north magnetic pole is in the blackout zone, Denver is not in either
+	mag, _ := w.MagneticField(egm96.NewLocationGeodetic(86.5, 162.9, 0), tt)
+	if mag.Zone()!=BlackoutZone {
+		t.Errorf("%sexpected the north magnetic pole to be in the blackout zone%s", red, reset)
+	}
+	mag, _ = w.MagneticField(egm96.NewLocationGeodetic(39.7, -105, 0), tt)
+	if mag.Zone()!=NormalZone {
+		t.Errorf("%sexpected Denver to be in the normal zone%s", red, reset)
+	}
+}
+
+func TestZoneBoundaryUnreached(t *testing.T) {
+	w, err := LoadModel("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := DecimalYear(2022.5).ToTime()
+
+	// H never reaches 100000 nT, so there is no boundary
+	if ring := w.zoneBoundary(100000, 1, tt); ring!=nil {
+		t.Errorf("%sexpected no boundary where H is never reached, got %d locations%s", red, len(ring), reset)
+	}
+
+	// H reaches 22000 nT within 45º of the weakest H on some bearings but not others,
+	// which are left out of the boundary
+	ring := w.zoneBoundary(22000, 1, tt)
+	if len(ring)<2 || len(ring)>=361 || !ring[0].Equals(ring[len(ring)-1]) {
+		t.Errorf("%sexpected a partial closed ring, got %d locations%s", red, len(ring), reset)
+	}
+	for _, loc := range ring {
+		mag, _ := w.MagneticField(loc, tt)
+		testDiff("partial boundary H", mag.H(), 22000, 0.01, t)
+	}
+}